@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	errDatabaseNotFound  = errors.New("database not found in session")
+	errUnsupportedFormat = errors.New("unsupported output format")
+)
+
+// runHeadless opens the database named dbName, runs query on it and writes
+// all results to stdout in the given format. The database is either a
+// connection profile from the configuration or a database from the session
+// file. If query is empty, it is read from stdin. tableName is only used for
+// SQL INSERT statements.
+func runHeadless(model *model, sessionFile, dbName, query, format, tableName string) error {
+	var exporter *exportFormat
+
+	if format != "table" {
+		f, ok := getExportFormat(format)
+		if !ok {
+			return fmt.Errorf("%s: %w", format, errUnsupportedFormat)
+		}
+
+		exporter = &f
+	}
+
+	view := newCLIView(os.Stdout, os.Stderr, exporter)
+	ctrl := newController(model, view)
+	model.setController(ctrl)
+
+	view.opts = exportOptions{
+		TableName: tableName,
+		QuoteIdent: func(ident string) string {
+			return quoteIdentifier(ctrl.getDriver(view.dbID), ident)
+		},
+	}
+
+	if _, ok := model.getProfile(dbName); ok {
+		if err := ctrl.openProfile(dbName); err != nil {
+			return err
+		}
+	} else {
+		session, err := loadSession(sessionFile)
+		if err != nil {
+			return err
+		}
+
+		db, err := findSessionDB(session, dbName)
+		if err != nil {
+			return err
+		}
+
+		if err := ctrl.openDatabase(db.Driver, db.ConnectParams, db.Profile); err != nil {
+			return err
+		}
+	}
+	defer ctrl.closeDatabase(view.dbID)
+
+	if query == "" {
+		queryData, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("reading query from stdin failed: %w", err)
+		}
+
+		query = string(queryData)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return ctrl.execQuery(ctx, view.dbID, query, nil)
+}
+
+func findSessionDB(session *sessionData, dbName string) (sessionDataDB, error) {
+	for _, db := range session.Databases {
+		drv, ok := supportedDrivers[db.Driver]
+		if !ok {
+			continue
+		}
+
+		if db.Profile == dbName || drv.DBInfoGenerator(db.ConnectParams, nil).Name() == dbName {
+			return db, nil
+		}
+	}
+
+	return sessionDataDB{}, fmt.Errorf("%s: %w", dbName, errDatabaseNotFound)
+}
+
+// cliView writes query results to an output stream instead of showing them
+// in the user interface.
+type cliView struct {
+	out     io.Writer
+	msgOut  io.Writer     // messages of statements that don't return rows
+	format  *exportFormat // nil for a plain text table
+	opts    exportOptions
+	dbID    string
+	results int
+}
+
+func newCLIView(out, msgOut io.Writer, format *exportFormat) *cliView {
+	return &cliView{
+		out:    out,
+		msgOut: msgOut,
+		format: format,
+	}
+}
+
+func (v *cliView) addDatabase(dbID, dbName string) {
+	v.dbID = dbID
+}
+
+func (v *cliView) clearQueryResults() {}
+
+func (v *cliView) addQueryResult(result *queryResult) error {
+	defer result.close()
+
+	if result.rows == nil {
+		fmt.Fprintln(v.msgOut, result.message)
+
+		return nil
+	}
+
+	if v.results > 0 {
+		fmt.Fprintln(v.out)
+	}
+
+	v.results++
+
+	if v.format == nil {
+		return v.writeTable(result)
+	}
+
+	return v.export(result)
+}
+
+func (v *cliView) export(result *queryResult) error {
+	w := v.format.NewWriter(v.out, v.opts)
+
+	if err := w.WriteHeader(result.columns); err != nil {
+		return err
+	}
+
+	if err := forEachRow(result, w.WriteRow); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+func (v *cliView) writeTable(result *queryResult) error {
+	w := tabwriter.NewWriter(v.out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, strings.Join(result.columns, "\t"))
+
+	if err := forEachRow(result, func(row []interface{}) error {
+		fields := make([]string, 0, len(row))
+		for _, value := range row {
+			fields = append(fields, valueString(value, "NULL"))
+		}
+
+		_, err := fmt.Fprintln(w, strings.Join(fields, "\t"))
+
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing table failed: %w", err)
+	}
+
+	return nil
+}
+
+func (v *cliView) getSession() *queriesData {
+	return nil
+}
+
+func (v *cliView) restoreSession(queries *queriesData) {}
+
+// forEachRow fetches all rows from result and calls f for each of them.
+func forEachRow(result *queryResult, f func(row []interface{}) error) error {
+	for {
+		rows, done, err := result.fetchRows(defaultPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if err := f(row); err != nil {
+				return err
+			}
+		}
+
+		if done {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunHeadlessReadsQueryFromStdin(t *testing.T) {
+	dir := t.TempDir()
+
+	sessionFile := filepath.Join(dir, "session.yml")
+	if err := os.WriteFile(sessionFile, []byte("databases:\n  - driver: sqlite\n    connect_params:\n      file: "+filepath.Join(dir, "test.db")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newModel()
+
+	// the passphrase of the credentials file must not be read from stdin.
+	t.Setenv("KOIOS_PASSPHRASE", "secret")
+
+	store, err := newCredentialStore(credentialsConfig{Store: "file"}, filepath.Join(dir, "credentials.enc"), readPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.setCredentialStore(store)
+
+	stdin, err := os.Open(writeTestFile(t, filepath.Join(dir, "query.sql"), "SELECT 1 AS x, 'a' AS y"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+
+	stdout, err := os.Create(filepath.Join(dir, "out.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+
+	origStdin, origStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdin, stdout
+
+	err = runHeadless(m, sessionFile, "test.db", "", "csv", "")
+
+	os.Stdin, os.Stdout = origStdin, origStdout
+
+	if err != nil {
+		t.Fatalf("runHeadless returned error: %v", err)
+	}
+
+	out, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != "x,y\n1,a\n" {
+		t.Errorf("runHeadless wrote %q, want %q", out, "x,y\n1,a\n")
+	}
+}
+
+func writeTestFile(t *testing.T, filename, content string) string {
+	t.Helper()
+
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var errClipboardCommandFailed = errors.New("clipboard command failed")
+
+type clipboardConfig struct {
+	// Command is run with the copied text on stdin, e.g. "xclip -selection
+	// clipboard", if the terminal can't be written to.
+	Command string `yaml:"command"`
+	// Always runs Command even if the terminal was written to, for
+	// terminals that ignore OSC 52.
+	Always bool `yaml:"always"`
+}
+
+// copyToSystemClipboard sets the clipboard of the terminal using an OSC 52
+// escape sequence, which also works over SSH. The configured clipboard
+// command is run as fallback if the terminal can't be opened or written to,
+// or in addition to it if cfg.Always is set.
+func copyToSystemClipboard(cfg clipboardConfig, text string) error {
+	ttyErr := writeOSC52("/dev/tty", text)
+	if ttyErr == nil && !cfg.Always {
+		return nil
+	}
+
+	if cfg.Command == "" {
+		return ttyErr
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("/bin/sh", "-c", cfg.Command)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %v: %s", cfg.Command, errClipboardCommandFailed, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// writeOSC52 writes the OSC 52 escape sequence that sets the clipboard to
+// text to the terminal device tty.
+func writeOSC52(tty, text string) error {
+	f, err := os.OpenFile(tty, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("opening terminal failed: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.WriteString(f, osc52Sequence(text)); err != nil {
+		return fmt.Errorf("writing to terminal failed: %w", err)
+	}
+
+	return nil
+}
+
+// osc52Sequence returns the escape sequence that sets the clipboard to text.
+func osc52Sequence(text string) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+}
+
+// copyScope is the part of a result that is copied.
+type copyScope int
+
+const (
+	copyCell copyScope = iota
+	copyRow
+	copyColumn
+	copySelection
+	copyAllRows
+)
+
+var copyScopeNames = []string{"Cell", "Row", "Column", "Selection", "All Rows"}
+
+// copyFormat formats copied values.
+type copyFormat struct {
+	Name   string
+	Format func(columns []string, rows [][]interface{}) (string, error)
+}
+
+var copyFormats = []copyFormat{
+	{Name: "Plain", Format: formatPlain},
+	{Name: "TSV", Format: formatWithExport("TSV")},
+	{Name: "CSV", Format: formatWithExport("CSV")},
+	{Name: "JSON", Format: formatWithExport("JSON")},
+	{Name: "SQL IN-list", Format: formatINList},
+}
+
+func copyFormatNames() []string {
+	names := make([]string, 0, len(copyFormats))
+	for _, format := range copyFormats {
+		names = append(names, format.Name)
+	}
+
+	return names
+}
+
+// formatPlain returns the values as text without a header, separated by
+// tabs and one row per line.
+func formatPlain(columns []string, rows [][]interface{}) (string, error) {
+	lines := make([]string, 0, len(rows))
+
+	for _, row := range rows {
+		fields := make([]string, 0, len(row))
+		for _, v := range row {
+			fields = append(fields, valueString(v, ""))
+		}
+
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatWithExport returns a function that formats values like exporting
+// them in the export format name does.
+func formatWithExport(name string) func(columns []string, rows [][]interface{}) (string, error) {
+	return func(columns []string, rows [][]interface{}) (string, error) {
+		format, ok := getExportFormat(name)
+		if !ok {
+			return "", fmt.Errorf("%s: %w", name, errUnsupportedFormat)
+		}
+
+		var buf strings.Builder
+
+		w := format.NewWriter(&buf, exportOptions{})
+
+		if err := w.WriteHeader(columns); err != nil {
+			return "", err
+		}
+
+		for _, row := range rows {
+			if err := w.WriteRow(row); err != nil {
+				return "", err
+			}
+		}
+
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+
+		return buf.String(), nil
+	}
+}
+
+// formatINList returns the values as list for an SQL IN condition. Rows of
+// more than one column become row values, e.g. ((1, 'a'), (2, 'b')).
+func formatINList(columns []string, rows [][]interface{}) (string, error) {
+	items := make([]string, 0, len(rows))
+
+	for _, row := range rows {
+		literals := make([]string, 0, len(row))
+		for _, v := range row {
+			literals = append(literals, sqlLiteral(v))
+		}
+
+		if len(literals) == 1 {
+			items = append(items, literals[0])
+		} else {
+			items = append(items, "("+strings.Join(literals, ", ")+")")
+		}
+	}
+
+	return "(" + strings.Join(items, ", ") + ")", nil
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+type completionKind int
+
+const (
+	completionColumn completionKind = iota
+	completionTable
+	completionKeyword
+)
+
+// completion is a candidate for completing the word at the cursor in the
+// query editor.
+type completion struct {
+	Text   string
+	Kind   completionKind
+	Detail string // column type, "table" or "keyword"
+}
+
+var sqlKeywords = []string{
+	"SELECT", "FROM", "WHERE", "AND", "OR", "NOT", "NULL", "IS", "IN", "LIKE", "BETWEEN", "EXISTS",
+	"JOIN", "INNER", "LEFT", "RIGHT", "FULL", "OUTER", "CROSS", "ON", "USING", "AS",
+	"GROUP BY", "ORDER BY", "HAVING", "LIMIT", "OFFSET", "DISTINCT", "ASC", "DESC",
+	"UNION", "ALL", "INTERSECT", "EXCEPT", "CASE", "WHEN", "THEN", "ELSE", "END",
+	"INSERT INTO", "VALUES", "UPDATE", "SET", "DELETE FROM", "RETURNING", "WITH",
+	"CREATE", "ALTER", "DROP", "TABLE", "VIEW", "INDEX", "PRIMARY KEY", "FOREIGN KEY", "REFERENCES", "DEFAULT",
+	"BEGIN", "COMMIT", "ROLLBACK", "EXPLAIN",
+	"COUNT", "SUM", "AVG", "MIN", "MAX", "COALESCE", "CAST", "TRUE", "FALSE",
+	"IF", "REPLACE", "TRUNCATE", "SCHEMA", "DATABASE", "FUNCTION", "TRIGGER", "SEQUENCE", "MATERIALIZED",
+	"UNIQUE", "CHECK", "CONSTRAINT", "GRANT", "REVOKE", "TRANSACTION", "START", "RECURSIVE",
+	"OVER", "PARTITION", "WINDOW", "ROWS", "FETCH", "NULLS", "ILIKE", "ANY", "SOME", "LATERAL",
+	"SHOW", "DESCRIBE", "PRAGMA", "ANALYZE", "VACUUM",
+}
+
+// reservedWords are words that can't be table aliases.
+var reservedWords = func() map[string]bool {
+	words := make(map[string]bool)
+
+	for _, keyword := range sqlKeywords {
+		for _, word := range strings.Fields(keyword) {
+			words[word] = true
+		}
+	}
+
+	words["BY"] = true
+	words["INTO"] = true
+	words["NATURAL"] = true
+
+	return words
+}()
+
+// tableKeywords are keywords that are followed by a table name.
+var tableKeywords = map[string]bool{
+	"FROM":   true,
+	"JOIN":   true,
+	"UPDATE": true,
+	"INTO":   true,
+	"TABLE":  true,
+}
+
+type tableRef struct {
+	Name  string
+	Alias string
+}
+
+// completionPrefix returns the start of the word that ends at pos in query,
+// and the qualifier that precedes it, e.g. "t" when completing "t.na".
+func completionPrefix(query string, pos int) (start int, qualifier string) {
+	start = pos
+	for start > 0 && isWordChar(query[start-1]) {
+		start--
+	}
+
+	if start == 0 || query[start-1] != '.' {
+		return start, ""
+	}
+
+	qualifierStart := start - 1
+	for qualifierStart > 0 && isWordChar(query[qualifierStart-1]) {
+		qualifierStart--
+	}
+
+	return start, query[qualifierStart : start-1]
+}
+
+// referencedTables returns the tables that stmt refers to after FROM, JOIN,
+// UPDATE, INTO and TABLE, together with their aliases.
+func referencedTables(stmt sqlStatement, dialect sqlDialect) []tableRef {
+	var tokens []sqlToken
+
+	for _, tok := range stmt.Tokens {
+		if tok.Kind != tokenWhitespace && tok.Kind != tokenComment {
+			tokens = append(tokens, tok)
+		}
+	}
+
+	var refs []tableRef
+
+	for idx := 0; idx < len(tokens); idx++ {
+		if tokens[idx].Kind != tokenWord || !tableKeywords[strings.ToUpper(tokens[idx].Text)] {
+			continue
+		}
+
+		for {
+			ref, next := parseTableRef(tokens, idx+1, dialect)
+			if ref.Name == "" {
+				break
+			}
+
+			refs = append(refs, ref)
+			idx = next - 1
+
+			// FROM may be followed by a comma-separated list of tables.
+			if next >= len(tokens) || tokens[next].Text != "," {
+				break
+			}
+
+			idx = next
+		}
+	}
+
+	return refs
+}
+
+// parseTableRef parses a possibly qualified table name and an optional
+// alias starting at tokens[idx]. It returns the index of the first token
+// after the table reference.
+func parseTableRef(tokens []sqlToken, idx int, dialect sqlDialect) (tableRef, int) {
+	var (
+		ref   tableRef
+		parts []string
+	)
+
+	for idx < len(tokens) {
+		tok := tokens[idx]
+		if (tok.Kind != tokenWord && tok.Kind != tokenQuotedIdent) || (tok.Kind == tokenWord && reservedWords[strings.ToUpper(tok.Text)]) {
+			break
+		}
+
+		parts = append(parts, identifierName(tok, dialect))
+		idx++
+
+		if idx >= len(tokens) || tokens[idx].Text != "." {
+			break
+		}
+
+		idx++
+	}
+
+	if len(parts) == 0 {
+		return ref, idx
+	}
+
+	ref.Name = strings.Join(parts, ".")
+
+	if idx < len(tokens) && strings.EqualFold(tokens[idx].Text, "AS") {
+		idx++
+	}
+
+	if idx < len(tokens) {
+		tok := tokens[idx]
+		if tok.Kind == tokenQuotedIdent || (tok.Kind == tokenWord && !reservedWords[strings.ToUpper(tok.Text)]) {
+			ref.Alias = identifierName(tok, dialect)
+			idx++
+		}
+	}
+
+	return ref, idx
+}
+
+// identifierName returns the name that an identifier token refers to.
+// Quoted identifiers are unquoted, unquoted ones are folded to lower case if
+// the dialect does so.
+func identifierName(tok sqlToken, dialect sqlDialect) string {
+	switch {
+	case tok.Kind == tokenQuotedIdent:
+		return unquoteIdentifier(tok.Text)
+	case dialect.LowerCaseIdents:
+		return strings.ToLower(tok.Text)
+	default:
+		return tok.Text
+	}
+}
+
+// unquoteIdentifier removes the quotes from a quoted identifier.
+func unquoteIdentifier(ident string) string {
+	if len(ident) < 2 {
+		return ident
+	}
+
+	switch quote := ident[0]; quote {
+	case '"', '`':
+		if ident[len(ident)-1] == quote {
+			return strings.ReplaceAll(ident[1:len(ident)-1], string([]byte{quote, quote}), string(quote))
+		}
+	case '[':
+		if ident[len(ident)-1] == ']' {
+			return ident[1 : len(ident)-1]
+		}
+	}
+
+	return ident
+}
+
+// findTable returns the table of tables that name refers to. name may be
+// qualified with a schema. A table whose name matches exactly is preferred
+// over one that only differs in case.
+func findTable(tables []string, name string) (string, bool) {
+	for _, table := range tables {
+		if table == name {
+			return table, true
+		}
+	}
+
+	for _, table := range tables {
+		if strings.EqualFold(table, name) {
+			return table, true
+		}
+	}
+
+	if idx := strings.LastIndexByte(name, '.'); idx >= 0 {
+		return findTable(tables, name[idx+1:])
+	}
+
+	return "", false
+}
+
+// filterCompletions returns the completions that start with prefix,
+// ignoring case.
+func filterCompletions(completions []completion, prefix string) []completion {
+	prefix = strings.ToLower(prefix)
+
+	var filtered []completion
+
+	for _, c := range completions {
+		if strings.HasPrefix(strings.ToLower(c.Text), prefix) {
+			filtered = append(filtered, c)
+		}
+	}
+
+	return filtered
+}
+
+// keywordCompletions returns all SQL keywords, in lower case if prefix is
+// in lower case.
+func keywordCompletions(prefix string) []completion {
+	lower := prefix != "" && prefix == strings.ToLower(prefix)
+
+	completions := make([]completion, 0, len(sqlKeywords))
+
+	for _, keyword := range sqlKeywords {
+		if lower {
+			keyword = strings.ToLower(keyword)
+		}
+
+		completions = append(completions, completion{Text: keyword, Kind: completionKeyword, Detail: "keyword"})
+	}
+
+	return completions
+}
+
+// schemaCache caches the tables and columns of open databases, so that the
+// database doesn't need to be queried for every completion.
+type schemaCache struct {
+	mtx     sync.Mutex
+	tables  map[string][]string            // tables by dbID
+	columns map[string]map[string][]column // columns by dbID and table
+}
+
+func newSchemaCache() *schemaCache {
+	return &schemaCache{
+		tables:  make(map[string][]string),
+		columns: make(map[string]map[string][]column),
+	}
+}
+
+func (c *schemaCache) getTables(dbID string, load func() ([]string, error)) ([]string, error) {
+	c.mtx.Lock()
+	tables, ok := c.tables[dbID]
+	c.mtx.Unlock()
+
+	if ok {
+		return tables, nil
+	}
+
+	tables, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(tables)
+
+	c.mtx.Lock()
+	c.tables[dbID] = tables
+	c.mtx.Unlock()
+
+	return tables, nil
+}
+
+func (c *schemaCache) getColumns(dbID, table string, load func() ([]column, error)) ([]column, error) {
+	c.mtx.Lock()
+	columns, ok := c.columns[dbID][table]
+	c.mtx.Unlock()
+
+	if ok {
+		return columns, nil
+	}
+
+	columns, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	if c.columns[dbID] == nil {
+		c.columns[dbID] = make(map[string][]column)
+	}
+	c.columns[dbID][table] = columns
+	c.mtx.Unlock()
+
+	return columns, nil
+}
+
+// invalidate removes all cached metadata of a database.
+func (c *schemaCache) invalidate(dbID string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	delete(c.tables, dbID)
+	delete(c.columns, dbID)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompletionPrefix(t *testing.T) {
+	tests := []struct {
+		query         string
+		pos           int
+		wantStart     int
+		wantQualifier string
+	}{
+		{query: "", pos: 0, wantStart: 0},
+		{query: "SEL", pos: 3, wantStart: 0},
+		{query: "SELECT na", pos: 9, wantStart: 7},
+		{query: "SELECT ", pos: 7, wantStart: 7},
+		{query: "SELECT t.na FROM t", pos: 11, wantStart: 9, wantQualifier: "t"},
+		{query: "SELECT users. FROM users", pos: 13, wantStart: 13, wantQualifier: "users"},
+		{query: "SELECT name FROM t", pos: 9, wantStart: 7},
+		{query: "SELECT .x", pos: 9, wantStart: 8},
+	}
+
+	for _, tt := range tests {
+		start, qualifier := completionPrefix(tt.query, tt.pos)
+		if start != tt.wantStart || qualifier != tt.wantQualifier {
+			t.Errorf("completionPrefix(%q, %d) = %d, %q, want %d, %q", tt.query, tt.pos, start, qualifier, tt.wantStart, tt.wantQualifier)
+		}
+	}
+}
+
+func TestReferencedTables(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []tableRef
+	}{
+		{query: "SELECT 1"},
+		{query: "SELECT * FROM users u WHERE u.id = 1", want: []tableRef{{Name: "users", Alias: "u"}}},
+		{query: "SELECT * FROM a AS x, public.b JOIN \"C\" c ON c.id = x.id", want: []tableRef{{Name: "a", Alias: "x"}, {Name: "public.b"}, {Name: "C", Alias: "c"}}},
+		{query: "UPDATE t SET a = 1", want: []tableRef{{Name: "t"}}},
+		{query: "INSERT INTO `my table` (a) VALUES (1)", want: []tableRef{{Name: "my table"}}},
+		{query: "SELECT * FROM t WHERE x IN (SELECT y FROM s)", want: []tableRef{{Name: "t"}, {Name: "s"}}},
+	}
+
+	for _, tt := range tests {
+		if got := referencedTables(splitStatements(tt.query, sqliteDialect)[0], sqliteDialect); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("referencedTables(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFindTable(t *testing.T) {
+	tables := []string{"users", "Orders", "audit.log", "Users"}
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{name: "USERS", want: "users", wantOK: true},
+		{name: "Users", want: "Users", wantOK: true},
+		{name: "public.orders", want: "Orders", wantOK: true},
+		{name: "audit.log", want: "audit.log", wantOK: true},
+		{name: "missing"},
+	}
+
+	for _, tt := range tests {
+		if got, ok := findTable(tables, tt.name); got != tt.want || ok != tt.wantOK {
+			t.Errorf("findTable(%q) = %q, %t, want %q, %t", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestKeywordCompletions(t *testing.T) {
+	completions := filterCompletions(keywordCompletions("sel"), "sel")
+	if len(completions) != 1 || completions[0].Text != "select" {
+		t.Errorf("completions for sel = %+v, want select", completions)
+	}
+
+	completions = filterCompletions(keywordCompletions("GROUP"), "GROUP")
+	if len(completions) != 1 || completions[0].Text != "GROUP BY" {
+		t.Errorf("completions for GROUP = %+v, want GROUP BY", completions)
+	}
+}
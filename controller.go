@@ -1,13 +1,31 @@
 package main
 
-import "log"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+var errProfileNotFound = errors.New("connection profile not found")
 
 type controller struct {
 	model *model
-	view  *mainView
+	view  view
+}
+
+// view is the part of the user interface that the controller pushes
+// updates to. It is implemented by mainView for the interactive mode, and
+// by cliView for the headless mode.
+type view interface {
+	addDatabase(dbID, dbName string)
+	clearQueryResults()
+	addQueryResult(result *queryResult) error
+	getSession() *queriesData
+	restoreSession(queries *queriesData)
 }
 
-func newController(model *model, view *mainView) *controller {
+func newController(model *model, view view) *controller {
 	return &controller{
 		model: model,
 		view:  view,
@@ -22,12 +40,64 @@ func (c *controller) getTableColumns(dbID, tbl string) ([]column, error) {
 	return c.model.getTableColumns(dbID, tbl)
 }
 
-func (c *controller) execQuery(dbID, q string) error {
-	return c.model.execQuery(dbID, q)
+func (c *controller) getSchemas(dbID string) ([]string, error) {
+	return c.model.getSchemas(dbID)
+}
+
+func (c *controller) getSchemaObjectKinds(dbID string) ([]objectKind, error) {
+	return c.model.getSchemaObjectKinds(dbID)
+}
+
+func (c *controller) getSchemaObjects(dbID, schema string, kind objectKind) ([]schemaObject, error) {
+	return c.model.getSchemaObjects(dbID, schema, kind)
+}
+
+func (c *controller) getSchemaColumns(dbID, schema, table string) ([]column, error) {
+	return c.model.getSchemaColumns(dbID, schema, table)
+}
+
+func (c *controller) getTableObjects(dbID, schema, table string, kind objectKind) ([]schemaObject, error) {
+	return c.model.getTableObjects(dbID, schema, table, kind)
+}
+
+func (c *controller) getDDL(dbID, schema, name string, kind objectKind) (string, error) {
+	return c.model.getDDL(dbID, schema, name, kind)
+}
+
+func (c *controller) getEditInfo(dbID, name string) (string, []column, []string, error) {
+	return c.model.getEditInfo(dbID, name)
+}
+
+func (c *controller) applyChanges(ctx context.Context, dbID string, stmts []editStatement) error {
+	return c.model.applyChanges(ctx, dbID, stmts)
+}
+
+func (c *controller) getCompletions(dbID, query string, pos int) (int, []completion, error) {
+	return c.model.getCompletions(dbID, query, pos)
+}
+
+func (c *controller) explainQuery(ctx context.Context, dbID, query string, analyze bool) (*planNode, error) {
+	return c.model.explainQuery(ctx, dbID, query, analyze)
+}
+
+func (c *controller) canAnalyzeQueries(dbID string) bool {
+	return c.model.canAnalyzeQueries(dbID)
+}
+
+func (c *controller) refreshSchema(dbID string) {
+	c.model.refreshSchema(dbID)
+}
+
+func (c *controller) getDriver(dbID string) string {
+	return c.model.getDriver(dbID)
+}
+
+func (c *controller) execQuery(ctx context.Context, dbID, q string, params map[string]interface{}) error {
+	return c.model.execQuery(ctx, dbID, q, params)
 }
 
-func (c *controller) openDatabase(driver string, params connectParams) error {
-	dbID, err := c.model.openDatabase(driver, params)
+func (c *controller) openDatabase(driver string, params connectParams, profile string) error {
+	dbID, err := c.model.openDatabase(driver, params, profile)
 	if err != nil {
 		return err
 	}
@@ -37,16 +107,12 @@ func (c *controller) openDatabase(driver string, params connectParams) error {
 	return nil
 }
 
-func (c *controller) clearResultTable() {
-	c.view.clearResultTable()
-}
-
-func (c *controller) setResultTableColumns(columns []string) {
-	c.view.setResultTableColumns(columns)
+func (c *controller) clearQueryResults() {
+	c.view.clearQueryResults()
 }
 
-func (c *controller) addResultTableRow(values []string) {
-	c.view.addResultTableRow(values)
+func (c *controller) addQueryResult(result *queryResult) error {
+	return c.view.addQueryResult(result)
 }
 
 func (c *controller) getSession() *sessionData {
@@ -58,7 +124,12 @@ func (c *controller) getSession() *sessionData {
 
 func (c *controller) restoreSession(session *sessionData) {
 	for _, db := range session.Databases {
-		if err := c.openDatabase(db.Driver, db.ConnectParams); err != nil {
+		// prefer the current settings of a profile over the ones stored in the session.
+		if profile, ok := c.model.getProfile(db.Profile); ok && db.Profile != "" {
+			db.Driver, db.ConnectParams = profile.Driver, profile.ConnectParams
+		}
+
+		if err := c.openDatabase(db.Driver, db.ConnectParams, db.Profile); err != nil {
 			log.Printf("Opening database %s %+v failed: %v", db.Driver, db.ConnectParams, err)
 		}
 	}
@@ -66,10 +137,71 @@ func (c *controller) restoreSession(session *sessionData) {
 	c.view.restoreSession(session.Queries)
 }
 
+func (c *controller) openProfile(name string) error {
+	profile, ok := c.model.getProfile(name)
+	if !ok {
+		return fmt.Errorf("%s: %w", name, errProfileNotFound)
+	}
+
+	return c.openDatabase(profile.Driver, profile.ConnectParams, profile.Name)
+}
+
+func (c *controller) getProfiles() []connectionProfile {
+	return c.model.getProfiles()
+}
+
+func (c *controller) checkSecrets(driver string, params connectParams) error {
+	return c.model.checkSecrets(driver, params)
+}
+
+func (c *controller) saveProfile(profile connectionProfile) error {
+	return c.model.saveProfile(profile)
+}
+
+func (c *controller) getHistory(limit int, dbID string) ([]historyEntry, error) {
+	return c.model.getHistory(limit, dbID)
+}
+
+func (c *controller) getSnippets() ([]snippet, error) {
+	return c.model.getSnippets()
+}
+
+func (c *controller) saveSnippet(s snippet) (snippet, error) {
+	return c.model.saveSnippet(s)
+}
+
+func (c *controller) getProfileName(dbID string) string {
+	return c.model.getProfileName(dbID)
+}
+
+func (c *controller) getConnectionKey(dbID string) string {
+	return c.model.getConnectionKey(dbID)
+}
+
 func (c *controller) getDatabaseName(dbID string) string {
 	return c.model.getDatabaseName(dbID)
 }
 
+func (c *controller) beginTx(dbID string) error {
+	return c.model.beginTx(dbID)
+}
+
+func (c *controller) commitTx(dbID string) error {
+	return c.model.commitTx(dbID)
+}
+
+func (c *controller) rollbackTx(dbID string) error {
+	return c.model.rollbackTx(dbID)
+}
+
+func (c *controller) inTransaction(dbID string) bool {
+	return c.model.inTransaction(dbID)
+}
+
+func (c *controller) openTransactions() []string {
+	return c.model.openTransactions()
+}
+
 func (c *controller) closeDatabase(dbID string) string {
 	return c.model.closeDatabase(dbID)
 }
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// credentialStore stores the secret connect parameters of databases apart
+// from the session file and the configuration.
+type credentialStore interface {
+	GetSecrets(key string) (map[string]string, error)
+	StoreSecrets(key string, secrets map[string]string) error
+}
+
+type credentialsConfig struct {
+	Store string `yaml:"store"` // session (default), file or external
+	File  string `yaml:"file"`  // location of encrypted credentials file
+}
+
+var (
+	errUnknownCredentialStore = errors.New("unknown credential store")
+	errWrongPassphrase        = errors.New("wrong passphrase or corrupted credentials file")
+	errSecretCommandFailed    = errors.New("secret command failed")
+	errNoPassphrase           = errors.New("no terminal to prompt for passphrase and KOIOS_PASSPHRASE is not set")
+	errSecretsNotStorable     = errors.New("external credential store can't store secrets")
+)
+
+// keepInSession returns whether secrets shall be kept in the session file
+// and configuration as they are, without using a credential store.
+func (cfg credentialsConfig) keepInSession() bool {
+	return cfg.Store == "" || cfg.Store == "session"
+}
+
+// newCredentialStore creates the credential store configured in cfg.
+// getPassphrase is only called if the store requires a passphrase.
+func newCredentialStore(cfg credentialsConfig, defaultFile string, getPassphrase func() (string, error)) (credentialStore, error) {
+	switch cfg.Store {
+	case "external":
+		return externalCredentialStore{}, nil
+	case "file":
+		filename := cfg.File
+		if filename == "" {
+			filename = defaultFile
+		}
+
+		passphrase, err := getPassphrase()
+		if err != nil {
+			return nil, err
+		}
+
+		return openFileCredentialStore(filename, passphrase)
+	default:
+		return nil, fmt.Errorf("%s: %w", cfg.Store, errUnknownCredentialStore)
+	}
+}
+
+// credentialKey identifies a database connection within a credential store
+// by its driver and non-secret connect parameters.
+func credentialKey(driver string, params connectParams) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	parts := []string{driver}
+
+	for _, k := range keys {
+		if !isSecretParam(driver, k) && !isSecretRef(k) {
+			parts = append(parts, k+"="+params[k])
+		}
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func isSecretParam(driver, param string) bool {
+	for _, secret := range supportedDrivers[driver].SecretParams {
+		if secret == param {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSecretRef returns whether param refers to a secret parameter instead of
+// containing it, e.g. password_cmd or password_env.
+func isSecretRef(param string) bool {
+	return strings.HasSuffix(param, "_cmd") || strings.HasSuffix(param, "_env")
+}
+
+// resolveSecrets fills in the secret connect parameters that are missing
+// from params. A secret is either taken from the command or environment
+// variable that the parameter's _cmd or _env counterpart refers to, or from
+// the credential store.
+func resolveSecrets(store credentialStore, driver string, params connectParams) (connectParams, error) {
+	var stored map[string]string
+
+	resolved := make(connectParams, len(params))
+	for k, v := range params {
+		resolved[k] = v
+	}
+
+	for _, secret := range supportedDrivers[driver].SecretParams {
+		switch {
+		case params[secret] != "":
+			continue
+		case params[secret+"_cmd"] != "":
+			value, err := runSecretCommand(params[secret+"_cmd"])
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[secret] = value
+		case params[secret+"_env"] != "":
+			resolved[secret] = os.Getenv(params[secret+"_env"])
+		case store != nil:
+			if stored == nil {
+				var err error
+
+				stored, err = store.GetSecrets(credentialKey(driver, params))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if value, ok := stored[secret]; ok {
+				resolved[secret] = value
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+// stripSecrets removes the secret connect parameters from params and puts
+// them into the credential store. Secrets that are resolved from a command
+// or an environment variable are only removed. If store is nil, all other
+// secrets are kept in params. The stripped parameters are returned even if
+// storing the secrets failed.
+func stripSecrets(store credentialStore, driver string, params connectParams) (connectParams, error) {
+	stripped := make(connectParams, len(params))
+	secrets := make(map[string]string)
+
+	for k, v := range params {
+		switch {
+		case !isSecretParam(driver, k):
+			stripped[k] = v
+		case params[k+"_cmd"] != "" || params[k+"_env"] != "":
+			// secret is resolved from reference when opening the database.
+		case store == nil:
+			stripped[k] = v
+		default:
+			secrets[k] = v
+		}
+	}
+
+	if store != nil && len(secrets) > 0 {
+		if err := store.StoreSecrets(credentialKey(driver, params), secrets); err != nil {
+			return stripped, err
+		}
+	}
+
+	return stripped, nil
+}
+
+// ttyDevice is the controlling terminal of the process.
+var ttyDevice = "/dev/tty"
+
+// readPassphrase reads the passphrase for the credentials file from the
+// environment variable KOIOS_PASSPHRASE, or prompts for it on the terminal.
+// The terminal is opened directly, so that stdin remains available for a
+// query that is piped to koios in headless mode.
+func readPassphrase() (string, error) {
+	if passphrase := os.Getenv("KOIOS_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+
+	tty, err := os.OpenFile(ttyDevice, os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errNoPassphrase, err)
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, "Passphrase for credentials file: ")
+
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+
+	fmt.Fprintln(tty)
+
+	if err != nil {
+		return "", fmt.Errorf("reading passphrase failed: %w", err)
+	}
+
+	return string(passphrase), nil
+}
+
+func runSecretCommand(command string) (string, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w: %v: %s", command, errSecretCommandFailed, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
+
+// externalCredentialStore doesn't store any secrets. All secrets need to be
+// provided through commands or environment variables.
+type externalCredentialStore struct{}
+
+func (externalCredentialStore) GetSecrets(key string) (map[string]string, error) {
+	return nil, nil
+}
+
+// StoreSecrets fails, as the secrets would otherwise be lost silently.
+func (externalCredentialStore) StoreSecrets(key string, secrets map[string]string) error {
+	params := make([]string, 0, len(secrets))
+	for param := range secrets {
+		params = append(params, param)
+	}
+
+	sort.Strings(params)
+
+	return fmt.Errorf("%w, provide %s through %s_cmd or %s_env instead",
+		errSecretsNotStorable, strings.Join(params, ", "), params[0], params[0])
+}
+
+// fileCredentialStore keeps secrets in a file that is encrypted with
+// AES-256-GCM, using a key that is derived from a passphrase using scrypt.
+// The file consists of the salt, the nonce and the encrypted JSON-encoded
+// secrets.
+type fileCredentialStore struct {
+	filename string
+	key      []byte
+	salt     []byte
+	secrets  map[string]map[string]string
+}
+
+const (
+	credentialsSaltLen = 16
+	credentialsKeyLen  = 32
+)
+
+func openFileCredentialStore(filename, passphrase string) (*fileCredentialStore, error) {
+	store := &fileCredentialStore{
+		filename: filename,
+		secrets:  make(map[string]map[string]string),
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		store.salt = make([]byte, credentialsSaltLen)
+		if _, err := io.ReadFull(rand.Reader, store.salt); err != nil {
+			return nil, fmt.Errorf("generating salt failed: %w", err)
+		}
+
+		if store.key, err = deriveCredentialsKey(passphrase, store.salt); err != nil {
+			return nil, err
+		}
+
+		return store, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("couldn't read credentials file %s: %w", filename, err)
+	}
+
+	if len(data) < credentialsSaltLen {
+		return nil, errWrongPassphrase
+	}
+
+	store.salt = data[:credentialsSaltLen]
+
+	store.key, err = deriveCredentialsKey(passphrase, store.salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := store.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[credentialsSaltLen:]
+	if len(data) < gcm.NonceSize() {
+		return nil, errWrongPassphrase
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+
+	if err := json.Unmarshal(plaintext, &store.secrets); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal credentials file %s: %w", filename, err)
+	}
+
+	return store, nil
+}
+
+func deriveCredentialsKey(passphrase string, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, credentialsKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key failed: %w", err)
+	}
+
+	return key, nil
+}
+
+func (s *fileCredentialStore) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher failed: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher failed: %w", err)
+	}
+
+	return gcm, nil
+}
+
+func (s *fileCredentialStore) GetSecrets(key string) (map[string]string, error) {
+	return s.secrets[key], nil
+}
+
+func (s *fileCredentialStore) StoreSecrets(key string, secrets map[string]string) error {
+	s.secrets[key] = secrets
+
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return fmt.Errorf("marshalling credentials failed: %w", err)
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("generating nonce failed: %w", err)
+	}
+
+	data := make([]byte, 0, len(s.salt)+len(nonce)+len(plaintext)+gcm.Overhead())
+	data = append(data, s.salt...)
+	data = append(data, nonce...)
+	data = gcm.Seal(data, nonce, plaintext, nil)
+
+	if err := ioutil.WriteFile(s.filename, data, 0600); err != nil {
+		return fmt.Errorf("storing credentials to %s failed: %w", s.filename, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileCredentialStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "credentials")
+
+	store, err := openFileCredentialStore(filename, "secret")
+	if err != nil {
+		t.Fatalf("opening new store returned error: %v", err)
+	}
+
+	secrets := map[string]string{"password": "hunter2"}
+	if err := store.StoreSecrets("postgres;db=x", secrets); err != nil {
+		t.Fatalf("StoreSecrets returned error: %v", err)
+	}
+
+	reopened, err := openFileCredentialStore(filename, "secret")
+	if err != nil {
+		t.Fatalf("reopening store returned error: %v", err)
+	}
+
+	got, err := reopened.GetSecrets("postgres;db=x")
+	if err != nil || !reflect.DeepEqual(got, secrets) {
+		t.Errorf("GetSecrets = %v, %v, want %v", got, err, secrets)
+	}
+
+	if _, err := openFileCredentialStore(filename, "wrong"); !errors.Is(err, errWrongPassphrase) {
+		t.Errorf("opening store with wrong passphrase returned error %v, want %v", err, errWrongPassphrase)
+	}
+}
+
+func TestStripAndResolveSecrets(t *testing.T) {
+	store, err := openFileCredentialStore(filepath.Join(t.TempDir(), "credentials"), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("KOIOS_TEST_PASSWORD", "from env")
+
+	tests := []struct {
+		name         string
+		store        credentialStore
+		params       connectParams
+		wantStripped connectParams
+	}{
+		{
+			name:         "secret is moved to store",
+			store:        store,
+			params:       connectParams{"host": "a", "password": "pw"},
+			wantStripped: connectParams{"host": "a"},
+		},
+		{
+			name:         "secret is kept without store",
+			params:       connectParams{"host": "b", "password": "pw"},
+			wantStripped: connectParams{"host": "b", "password": "pw"},
+		},
+		{
+			name:         "secret is resolved from environment",
+			store:        store,
+			params:       connectParams{"host": "c", "password": "from env", "password_env": "KOIOS_TEST_PASSWORD"},
+			wantStripped: connectParams{"host": "c", "password_env": "KOIOS_TEST_PASSWORD"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stripped, err := stripSecrets(tt.store, "postgres", tt.params)
+			if err != nil {
+				t.Fatalf("stripSecrets returned error: %v", err)
+			}
+
+			if !reflect.DeepEqual(stripped, tt.wantStripped) {
+				t.Errorf("stripSecrets = %v, want %v", stripped, tt.wantStripped)
+			}
+
+			resolved, err := resolveSecrets(tt.store, "postgres", stripped)
+			if err != nil {
+				t.Fatalf("resolveSecrets returned error: %v", err)
+			}
+
+			if !reflect.DeepEqual(resolved, tt.params) {
+				t.Errorf("resolveSecrets = %v, want %v", resolved, tt.params)
+			}
+		})
+	}
+}
+
+func TestExternalCredentialStore(t *testing.T) {
+	if _, err := stripSecrets(externalCredentialStore{}, "postgres", connectParams{"host": "a", "password": "pw"}); !errors.Is(err, errSecretsNotStorable) {
+		t.Errorf("stripSecrets of plain secret returned error %v, want %v", err, errSecretsNotStorable)
+	}
+
+	t.Setenv("KOIOS_TEST_PASSWORD", "from env")
+
+	if _, err := stripSecrets(externalCredentialStore{}, "postgres", connectParams{"host": "a", "password": "from env", "password_env": "KOIOS_TEST_PASSWORD"}); err != nil {
+		t.Errorf("stripSecrets of secret from environment returned error: %v", err)
+	}
+}
+
+func TestReadPassphrase(t *testing.T) {
+	t.Setenv("KOIOS_PASSPHRASE", "from env")
+
+	if passphrase, err := readPassphrase(); err != nil || passphrase != "from env" {
+		t.Errorf("readPassphrase = %q, %v, want passphrase from environment", passphrase, err)
+	}
+
+	t.Setenv("KOIOS_PASSPHRASE", "")
+
+	orig := ttyDevice
+	ttyDevice = filepath.Join(t.TempDir(), "tty")
+
+	defer func() { ttyDevice = orig }()
+
+	if _, err := readPassphrase(); !errors.Is(err, errNoPassphrase) {
+		t.Errorf("readPassphrase without terminal returned error %v, want %v", err, errNoPassphrase)
+	}
+}
+
+func TestCredentialKey(t *testing.T) {
+	a := credentialKey("postgres", connectParams{"host": "h", "db": "d", "password": "x", "password_cmd": "pass"})
+	b := credentialKey("postgres", connectParams{"db": "d", "host": "h"})
+
+	if a != b || a != "postgres;db=d;host=h" {
+		t.Errorf("credentialKey returned %q and %q, want postgres;db=d;host=h", a, b)
+	}
+}
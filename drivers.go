@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"net"
 	"net/url"
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/rivo/tview"
 )
@@ -19,9 +23,13 @@ var supportedDrivers = map[string]struct {
 	DBInfoGenerator  func(params connectParams, db *sqlx.DB) dbInfo
 	AddInputFields   func(form *tview.Form)
 	GetConnectParams func(form *tview.Form) connectParams
+	QueryOnly        bool     // driver doesn't report results for Exec, so all statements are run as queries.
+	SecretParams     []string // connect parameters that must not be stored in session file or configuration.
+	Dialect          sqlDialect
 }{
 	"sqlite": {
-		Name: "SQLite",
+		Name:    "SQLite",
+		Dialect: sqlDialect{BacktickIdents: true, BlockBodies: true},
 		DSNGenerator: func(params connectParams) string {
 			return params["file"]
 		},
@@ -43,7 +51,9 @@ var supportedDrivers = map[string]struct {
 		},
 	},
 	"postgres": {
-		Name: "PostgreSQL",
+		Name:         "PostgreSQL",
+		SecretParams: []string{"password"},
+		Dialect:      sqlDialect{DollarQuotes: true, BlockBodies: true, DollarParams: true, LowerCaseIdents: true},
 		DSNGenerator: func(params connectParams) string {
 			return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
 				params["user"], params["password"], params["host"], params["port"], params["db"], params["ssl_mode"])
@@ -82,8 +92,61 @@ var supportedDrivers = map[string]struct {
 			}
 		},
 	},
+	"mysql": {
+		Name:         "MySQL/MariaDB",
+		SecretParams: []string{"password"},
+		Dialect:      sqlDialect{BacktickIdents: true, HashComments: true, BackslashEscapes: true, DoubleQuotedStrs: true, BlockBodies: true},
+		DSNGenerator: func(params connectParams) string {
+			cfg := mysql.NewConfig()
+			cfg.User = params["user"]
+			cfg.Passwd = params["password"]
+			cfg.Net = "tcp"
+			cfg.Addr = net.JoinHostPort(params["host"], params["port"])
+			cfg.DBName = params["db"]
+			cfg.TLSConfig = params["tls"]
+			cfg.ParseTime = true // return DATE and DATETIME as time.Time rather than text.
+
+			return cfg.FormatDSN()
+		},
+		DBInfoGenerator: func(params connectParams, db *sqlx.DB) dbInfo {
+			return &mysqlDbInfo{Params: params, DB: db}
+		},
+		AddInputFields: func(form *tview.Form) {
+			form.
+				AddInputField("Database", "", 30, nil, nil).
+				AddInputField("User", "", 30, nil, nil).
+				AddPasswordField("Password", "", 30, '*', nil).
+				AddInputField("Host", "localhost", 30, nil, nil).
+				AddInputField("Port", "3306", 30, func(textToCheck string, lastChar rune) bool {
+					i, err := strconv.ParseUint(textToCheck, 10, 64)
+
+					return err == nil && i >= 1 && i <= 65535
+				}, nil).
+				AddDropDown("TLS Mode", []string{"false", "preferred", "true", "skip-verify"}, 0, nil)
+		},
+		GetConnectParams: func(form *tview.Form) connectParams {
+			database := form.GetFormItem(0).(*tview.InputField).GetText()
+			user := form.GetFormItem(1).(*tview.InputField).GetText()
+			password := form.GetFormItem(2).(*tview.InputField).GetText()
+			host := form.GetFormItem(3).(*tview.InputField).GetText()
+			port := form.GetFormItem(4).(*tview.InputField).GetText()
+			_, tlsMode := form.GetFormItem(5).(*tview.DropDown).GetCurrentOption()
+
+			return connectParams{
+				"db":       database,
+				"user":     user,
+				"password": password,
+				"host":     host,
+				"port":     port,
+				"tls":      tlsMode,
+			}
+		},
+	},
 	"athena": {
-		Name: "Athena",
+		Name:         "Athena",
+		QueryOnly:    true,
+		SecretParams: []string{"secret_access_key"},
+		Dialect:      sqlDialect{BacktickIdents: true},
 		DSNGenerator: func(params connectParams) string {
 			values := make(url.Values)
 			for k, v := range params {
@@ -135,6 +198,51 @@ func supportedDriverList() []string {
 	return drivers
 }
 
+// driverDialect returns the SQL dialect of a driver, or the generic dialect
+// if the driver is unknown.
+func driverDialect(driver string) sqlDialect {
+	if drv, ok := supportedDrivers[driver]; ok {
+		return drv.Dialect
+	}
+
+	return genericDialect
+}
+
+// quoteIdentifier quotes an identifier such as a table or column name for
+// use in SQL statements of the given driver.
+func quoteIdentifier(driver, ident string) string {
+	if driver == "mysql" {
+		return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+	}
+
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+// scanSchemaObjects scans rows of object names and details, which may be
+// NULL.
+func scanSchemaObjects(rows *sql.Rows) ([]schemaObject, error) {
+	var objects []schemaObject
+
+	for rows.Next() {
+		var (
+			name   string
+			detail sql.NullString
+		)
+
+		if err := rows.Scan(&name, &detail); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		objects = append(objects, schemaObject{Name: name, Detail: detail.String})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over object list failed: %w", err)
+	}
+
+	return objects, nil
+}
+
 type dbInfo interface {
 	Driver() string
 	ConnectParams() connectParams
@@ -196,29 +304,93 @@ func (i *sqliteDbInfo) GetTables() ([]string, error) {
 	return tables, nil
 }
 
+// GetTableColumns lists the columns of a table that may be qualified with
+// its schema.
 func (i *sqliteDbInfo) GetTableColumns(tbl string) ([]column, error) {
-	rows, err := i.DB.Query("PRAGMA table_info(" + tbl + ")")
+	if schema, table, ok := strings.Cut(tbl, "."); ok {
+		return i.GetColumns(schema, table)
+	}
+
+	return i.GetColumns("", tbl)
+}
+
+func (i *sqliteDbInfo) GetPrimaryKey(tbl string) ([]string, error) {
+	query, args := "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", []interface{}{tbl}
+	if schema, table, ok := strings.Cut(tbl, "."); ok {
+		query, args = "SELECT name FROM pragma_table_info(?, ?) WHERE pk > 0 ORDER BY pk", []interface{}{table, schema}
+	}
+
+	var columns []string
+
+	if err := i.DB.Select(&columns, query, args...); err != nil {
+		return nil, fmt.Errorf("querying primary key of %s failed: %w", tbl, err)
+	}
+
+	return columns, nil
+}
+
+func (i *sqliteDbInfo) GetSchemas() ([]string, error) {
+	var schemas []string
+
+	if err := i.DB.Select(&schemas, "SELECT name FROM pragma_database_list ORDER BY seq"); err != nil {
+		return nil, fmt.Errorf("listing schemas failed: %w", err)
+	}
+
+	return schemas, nil
+}
+
+func (i *sqliteDbInfo) SchemaObjectKinds() []objectKind {
+	return []objectKind{objectTables, objectViews}
+}
+
+func (i *sqliteDbInfo) GetSchemaObjects(schema string, kind objectKind) ([]schemaObject, error) {
+	var typ string
+
+	switch kind {
+	case objectTables:
+		typ = "table"
+	case objectViews:
+		typ = "view"
+	default:
+		return nil, nil
+	}
+
+	rows, err := i.DB.Query(`
+			SELECT name, NULL FROM `+quoteIdentifier("sqlite", schema)+`.sqlite_master
+			WHERE type = ? AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+			ORDER BY name`,
+		typ)
 	if err != nil {
-		return nil, fmt.Errorf("querying columns for %s failed: %w", tbl, err)
+		return nil, fmt.Errorf("listing %s failed: %w", strings.ToLower(kind.String()), err)
+	}
+	defer rows.Close()
+
+	return scanSchemaObjects(rows)
+}
+
+// GetColumns lists the columns of a table. If schema is empty, the table
+// is looked up in all schemas.
+func (i *sqliteDbInfo) GetColumns(schema, table string) ([]column, error) {
+	query, args := "SELECT name, type FROM pragma_table_info(?, ?) ORDER BY cid", []interface{}{table, schema}
+	if schema == "" {
+		query, args = "SELECT name, type FROM pragma_table_info(?) ORDER BY cid", []interface{}{table}
+	}
+
+	rows, err := i.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying columns for %s failed: %w", table, err)
 	}
 	defer rows.Close()
 
 	var cols []column
 
 	for rows.Next() {
-		var (
-			cid       int
-			name, typ string
-			notNull   int
-			dfltValue sql.NullString
-			pk        int
-		)
-
-		if err := rows.Scan(&cid, &name, &typ, &notNull, &dfltValue, &pk); err != nil {
+		var col column
+		if err := rows.Scan(&col.Name, &col.Type); err != nil {
 			return nil, fmt.Errorf("scan failed: %w", err)
 		}
 
-		cols = append(cols, column{Name: name, Type: typ})
+		cols = append(cols, col)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -228,6 +400,98 @@ func (i *sqliteDbInfo) GetTableColumns(tbl string) ([]column, error) {
 	return cols, nil
 }
 
+// GetTableObjects lists indexes with their columns, foreign keys and
+// triggers of a table. Foreign keys have no name in SQLite, so they are
+// named after the columns that they consist of.
+func (i *sqliteDbInfo) GetTableObjects(schema, table string, kind objectKind) ([]schemaObject, error) {
+	var (
+		query string
+		args  []interface{}
+	)
+
+	switch kind {
+	case objectIndexes:
+		query = `
+			SELECT il.name,
+				CASE il.origin WHEN 'pk' THEN 'PRIMARY KEY, ' ELSE CASE il."unique" WHEN 1 THEN 'UNIQUE, ' ELSE '' END END ||
+				group_concat(ii.name, ', ')
+			FROM pragma_index_list(?, ?) il
+			LEFT JOIN pragma_index_info(il.name, ?) ii
+			GROUP BY il.name
+			ORDER BY il.name`
+		args = []interface{}{table, schema, schema}
+	case objectForeignKeys:
+		query = `
+			SELECT group_concat("from", ', ') || ' → ' || "table" ||
+				CASE WHEN count("to") > 0 THEN '(' || group_concat("to", ', ') || ')' ELSE '' END,
+				NULL
+			FROM pragma_foreign_key_list(?, ?)
+			GROUP BY id
+			ORDER BY id`
+		args = []interface{}{table, schema}
+	case objectTriggers:
+		query = `
+			SELECT name, NULL FROM ` + quoteIdentifier("sqlite", schema) + `.sqlite_master
+			WHERE type = 'trigger' AND tbl_name = ?
+			ORDER BY name`
+		args = []interface{}{table}
+	default:
+		return nil, nil
+	}
+
+	rows, err := i.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s of %s failed: %w", strings.ToLower(kind.String()), table, err)
+	}
+	defer rows.Close()
+
+	return scanSchemaObjects(rows)
+}
+
+// GetDDL returns the statements that created an object. The DDL of a table
+// includes its indexes and triggers.
+func (i *sqliteDbInfo) GetDDL(schema, name string, kind objectKind) (string, error) {
+	if schema == "" {
+		schema = "main"
+	}
+
+	query := `SELECT sql FROM ` + quoteIdentifier("sqlite", schema) + `.sqlite_master WHERE sql IS NOT NULL AND `
+
+	switch kind {
+	case objectTables:
+		query += `tbl_name = ? AND type IN ('table', 'index', 'trigger')
+			ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END, name`
+	case objectViews:
+		query += `name = ? AND type = 'view'`
+	case objectIndexes:
+		query += `name = ? AND type = 'index'`
+	default:
+		return "", errNoDDL
+	}
+
+	var stmts []string
+
+	if err := i.DB.Select(&stmts, query, name); err != nil {
+		return "", fmt.Errorf("querying DDL of %s failed: %w", name, err)
+	}
+
+	if len(stmts) == 0 {
+		return "", fmt.Errorf("%s: %w", name, errNoDDL)
+	}
+
+	return strings.Join(stmts, ";\n\n") + ";\n", nil
+}
+
+func (i *sqliteDbInfo) ExplainQuery(ctx context.Context, conn sqlx.QueryerContext, query string) (*planNode, error) {
+	rows, err := conn.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query)
+	if err != nil {
+		return nil, fmt.Errorf("explaining query failed: %w", err)
+	}
+	defer rows.Close()
+
+	return parseSQLitePlan(rows)
+}
+
 type pgDbInfo struct {
 	Params connectParams
 	DB     *sqlx.DB
@@ -249,8 +513,324 @@ func (i *pgDbInfo) Conn() *sqlx.DB {
 	return i.DB
 }
 
+// GetTables lists the tables and views of all schemas except the system
+// schemas. Tables of schemas that aren't in the search path are qualified
+// with their schema.
 func (i *pgDbInfo) GetTables() ([]string, error) {
-	rows, err := i.DB.Query("SELECT table_name FROM information_schema.tables WHERE table_schema = 'public'")
+	var tables []string
+
+	if err := i.DB.Select(&tables, `
+			SELECT CASE WHEN table_schema = ANY(current_schemas(false)) THEN table_name ELSE table_schema || '.' || table_name END
+			FROM information_schema.tables
+			WHERE table_schema NOT IN ('pg_catalog', 'information_schema')`); err != nil {
+		return nil, fmt.Errorf("listing tables failed: %w", err)
+	}
+
+	return tables, nil
+}
+
+// GetTableColumns lists the columns of a table that may be qualified with
+// its schema. Unqualified tables are looked up in the search path.
+func (i *pgDbInfo) GetTableColumns(tbl string) ([]column, error) {
+	if schema, table, ok := strings.Cut(tbl, "."); ok {
+		return i.GetColumns(schema, table)
+	}
+
+	return i.getColumns(quoteIdentifier("postgres", tbl))
+}
+
+func (i *pgDbInfo) GetColumns(schema, table string) ([]column, error) {
+	return i.getColumns(qualifiedName("postgres", schema, table))
+}
+
+// getColumns lists the columns of a relation, which unlike
+// information_schema.columns includes materialized views.
+func (i *pgDbInfo) getColumns(relation string) ([]column, error) {
+	rows, err := i.DB.Query(`
+			SELECT attname, format_type(atttypid, atttypmod)
+			FROM pg_attribute
+			WHERE attrelid = to_regclass($1) AND attnum > 0 AND NOT attisdropped
+			ORDER BY attnum`,
+		relation)
+	if err != nil {
+		return nil, fmt.Errorf("listing table columns failed: %w", err)
+	}
+	defer rows.Close()
+
+	var cols []column
+
+	for rows.Next() {
+		var (
+			col string
+			typ string
+		)
+
+		if err := rows.Scan(&col, &typ); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		cols = append(cols, column{Name: col, Type: typ})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over table column list failed: %w", err)
+	}
+
+	return cols, nil
+}
+
+// GetPrimaryKey returns the columns of the primary key constraint of a
+// table that may be qualified with its schema.
+func (i *pgDbInfo) GetPrimaryKey(tbl string) ([]string, error) {
+	relation := quoteTableName(func(ident string) string {
+		return quoteIdentifier("postgres", ident)
+	}, tbl)
+
+	var columns []string
+
+	if err := i.DB.Select(&columns, `
+			SELECT a.attname
+			FROM pg_constraint c
+			CROSS JOIN LATERAL unnest(c.conkey) WITH ORDINALITY AS k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+			WHERE c.conrelid = to_regclass($1) AND c.contype = 'p'
+			ORDER BY k.ord`,
+		relation); err != nil {
+		return nil, fmt.Errorf("querying primary key of %s failed: %w", tbl, err)
+	}
+
+	return columns, nil
+}
+
+// GetSchemas lists all schemas except the schemas of TOAST and temporary
+// tables, with the system schemas last.
+func (i *pgDbInfo) GetSchemas() ([]string, error) {
+	var schemas []string
+
+	if err := i.DB.Select(&schemas, `
+			SELECT nspname FROM pg_namespace
+			WHERE nspname NOT LIKE 'pg\_toast%' AND nspname NOT LIKE 'pg\_temp\_%'
+			ORDER BY nspname IN ('pg_catalog', 'information_schema'), nspname`); err != nil {
+		return nil, fmt.Errorf("listing schemas failed: %w", err)
+	}
+
+	return schemas, nil
+}
+
+func (i *pgDbInfo) SchemaObjectKinds() []objectKind {
+	return []objectKind{objectTables, objectViews, objectMaterializedViews, objectFunctions, objectSequences}
+}
+
+// pgRelationKinds are the values of pg_class.relkind by object kind.
+var pgRelationKinds = map[objectKind]string{
+	objectTables:            "'r', 'p', 'f'",
+	objectViews:             "'v'",
+	objectMaterializedViews: "'m'",
+	objectSequences:         "'S'",
+}
+
+func (i *pgDbInfo) GetSchemaObjects(schema string, kind objectKind) ([]schemaObject, error) {
+	var query string
+
+	if relKinds, ok := pgRelationKinds[kind]; ok {
+		query = `
+			SELECT c.relname, NULL
+			FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relkind IN (` + relKinds + `)
+			ORDER BY c.relname`
+	} else if kind == objectFunctions {
+		query = `
+			SELECT p.proname || '(' || pg_get_function_identity_arguments(p.oid) || ')',
+				'returns ' || pg_get_function_result(p.oid)
+			FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE n.nspname = $1
+			ORDER BY 1`
+	} else {
+		return nil, nil
+	}
+
+	rows, err := i.DB.Query(query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s failed: %w", strings.ToLower(kind.String()), err)
+	}
+	defer rows.Close()
+
+	return scanSchemaObjects(rows)
+}
+
+func (i *pgDbInfo) GetTableObjects(schema, table string, kind objectKind) ([]schemaObject, error) {
+	var query string
+
+	switch kind {
+	case objectIndexes:
+		query = `
+			SELECT c.relname,
+				CASE WHEN i.indisprimary THEN 'PRIMARY KEY, ' WHEN i.indisunique THEN 'UNIQUE, ' ELSE '' END ||
+				regexp_replace(pg_get_indexdef(i.indexrelid), '^.* USING ', '')
+			FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+			WHERE i.indrelid = to_regclass($1)
+			ORDER BY c.relname`
+	case objectForeignKeys:
+		query = `
+			SELECT conname, pg_get_constraintdef(oid)
+			FROM pg_constraint
+			WHERE contype = 'f' AND conrelid = to_regclass($1)
+			ORDER BY conname`
+	case objectTriggers:
+		query = `
+			SELECT tgname, regexp_replace(pg_get_triggerdef(oid), '^CREATE .*?TRIGGER \S+ ', '')
+			FROM pg_trigger
+			WHERE tgrelid = to_regclass($1) AND NOT tgisinternal
+			ORDER BY tgname`
+	default:
+		return nil, nil
+	}
+
+	rows, err := i.DB.Query(query, qualifiedName("postgres", schema, table))
+	if err != nil {
+		return nil, fmt.Errorf("listing %s of %s failed: %w", strings.ToLower(kind.String()), table, err)
+	}
+	defer rows.Close()
+
+	return scanSchemaObjects(rows)
+}
+
+// GetDDL returns the DDL of a view or an index as PostgreSQL reports it.
+// The DDL of a table is generated from its columns, constraints and the
+// indexes that don't belong to constraints.
+func (i *pgDbInfo) GetDDL(schema, name string, kind objectKind) (string, error) {
+	relation := qualifiedName("postgres", schema, name)
+
+	var (
+		prefix string
+		ddl    sql.NullString
+		err    error
+	)
+
+	switch kind {
+	case objectTables:
+		return i.tableDDL(relation)
+	case objectViews:
+		prefix = "CREATE OR REPLACE VIEW " + relation + " AS\n"
+		err = i.DB.Get(&ddl, "SELECT pg_get_viewdef(to_regclass($1), true)", relation)
+	case objectMaterializedViews:
+		prefix = "CREATE MATERIALIZED VIEW " + relation + " AS\n"
+		err = i.DB.Get(&ddl, "SELECT pg_get_viewdef(to_regclass($1), true)", relation)
+	case objectIndexes:
+		err = i.DB.Get(&ddl, "SELECT pg_get_indexdef(to_regclass($1)) || ';'", relation)
+	default:
+		return "", errNoDDL
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("querying DDL of %s failed: %w", name, err)
+	}
+
+	if !ddl.Valid {
+		return "", fmt.Errorf("%s: %w", name, errNoDDL)
+	}
+
+	return prefix + ddl.String + "\n", nil
+}
+
+func (i *pgDbInfo) tableDDL(relation string) (string, error) {
+	var defs []string
+
+	if err := i.DB.Select(&defs, `
+			SELECT quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod) ||
+				CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END ||
+				COALESCE(' DEFAULT ' || pg_get_expr(d.adbin, d.adrelid), '')
+			FROM pg_attribute a
+			LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+			WHERE a.attrelid = to_regclass($1) AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum`,
+		relation); err != nil {
+		return "", fmt.Errorf("querying columns of %s failed: %w", relation, err)
+	}
+
+	if len(defs) == 0 {
+		return "", fmt.Errorf("%s: %w", relation, errNoDDL)
+	}
+
+	var constraints []string
+
+	if err := i.DB.Select(&constraints, `
+			SELECT 'CONSTRAINT ' || quote_ident(conname) || ' ' || pg_get_constraintdef(oid)
+			FROM pg_constraint
+			WHERE conrelid = to_regclass($1)
+			ORDER BY contype <> 'p', contype <> 'u', conname`,
+		relation); err != nil {
+		return "", fmt.Errorf("querying constraints of %s failed: %w", relation, err)
+	}
+
+	var indexes []string
+
+	if err := i.DB.Select(&indexes, `
+			SELECT pg_get_indexdef(i.indexrelid) || ';'
+			FROM pg_index i
+			WHERE i.indrelid = to_regclass($1)
+				AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = i.indexrelid)
+			ORDER BY i.indexrelid::regclass::text`,
+		relation); err != nil {
+		return "", fmt.Errorf("querying indexes of %s failed: %w", relation, err)
+	}
+
+	var ddl strings.Builder
+
+	ddl.WriteString("CREATE TABLE " + relation + " (\n\t")
+	ddl.WriteString(strings.Join(append(defs, constraints...), ",\n\t"))
+	ddl.WriteString("\n);\n")
+
+	for _, index := range indexes {
+		ddl.WriteString("\n" + index + "\n")
+	}
+
+	return ddl.String(), nil
+}
+
+func (i *pgDbInfo) ExplainQuery(ctx context.Context, conn sqlx.QueryerContext, query string) (*planNode, error) {
+	return explainPgQuery(ctx, conn, "EXPLAIN (FORMAT JSON) "+query)
+}
+
+// ExplainAnalyzeQuery runs the query with EXPLAIN ANALYZE to get the actual
+// times and row counts.
+func (i *pgDbInfo) ExplainAnalyzeQuery(ctx context.Context, conn sqlx.QueryerContext, query string) (*planNode, error) {
+	return explainPgQuery(ctx, conn, "EXPLAIN (ANALYZE, FORMAT JSON) "+query)
+}
+
+func explainPgQuery(ctx context.Context, conn sqlx.QueryerContext, explain string) (*planNode, error) {
+	var plan []byte
+
+	if err := conn.QueryRowxContext(ctx, explain).Scan(&plan); err != nil {
+		return nil, fmt.Errorf("explaining query failed: %w", err)
+	}
+
+	return parsePgPlan(plan)
+}
+
+type mysqlDbInfo struct {
+	Params connectParams
+	DB     *sqlx.DB
+}
+
+func (i *mysqlDbInfo) Driver() string {
+	return "mysql"
+}
+
+func (i *mysqlDbInfo) ConnectParams() connectParams {
+	return i.Params
+}
+
+func (i *mysqlDbInfo) Name() string {
+	return fmt.Sprintf("%s/%s", i.Params["host"], i.Params["db"])
+}
+
+func (i *mysqlDbInfo) Conn() *sqlx.DB {
+	return i.DB
+}
+
+func (i *mysqlDbInfo) GetTables() ([]string, error) {
+	rows, err := i.DB.Query("SELECT table_name FROM information_schema.tables WHERE table_schema = ?", i.Params["db"])
 	if err != nil {
 		return nil, fmt.Errorf("listing tables failed: %w", err)
 	}
@@ -274,12 +854,13 @@ func (i *pgDbInfo) GetTables() ([]string, error) {
 	return tables, nil
 }
 
-func (i *pgDbInfo) GetTableColumns(tbl string) ([]column, error) {
+func (i *mysqlDbInfo) GetTableColumns(tbl string) ([]column, error) {
 	rows, err := i.DB.Query(`
-			select column_name, data_type 
+			select column_name, column_type
 			from information_schema.columns
-			where table_schema = 'public' and table_name = $1`,
-		tbl)
+			where table_schema = ? and table_name = ?
+			order by ordinal_position`,
+		i.Params["db"], tbl)
 	if err != nil {
 		return nil, fmt.Errorf("listing table columns failed: %w", err)
 	}
@@ -393,3 +974,53 @@ func (i *athenaDbInfo) GetTableColumns(table string) ([]column, error) {
 
 	return cols, nil
 }
+
+// GetDDL returns the output of SHOW CREATE TABLE or SHOW CREATE VIEW.
+func (i *athenaDbInfo) GetDDL(schema, name string, kind objectKind) (string, error) {
+	var query string
+
+	// unlike queries, DDL statements quote identifiers with backticks like MySQL.
+	quoted := quoteIdentifier("mysql", name)
+
+	switch kind {
+	case objectTables:
+		query = "SHOW CREATE TABLE " + quoted
+	case objectViews:
+		query = "SHOW CREATE VIEW " + quoted
+	default:
+		return "", errNoDDL
+	}
+
+	var lines []string
+
+	if err := i.DB.Select(&lines, query); err != nil {
+		return "", fmt.Errorf("querying DDL of %s failed: %w", name, err)
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+func (i *athenaDbInfo) ExplainQuery(ctx context.Context, conn sqlx.QueryerContext, query string) (*planNode, error) {
+	rows, err := conn.QueryContext(ctx, "EXPLAIN "+query)
+	if err != nil {
+		return nil, fmt.Errorf("explaining query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []string
+
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over query plan failed: %w", err)
+	}
+
+	return parseIndentedPlan(lines)
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	errNotEditable   = errors.New("result can't be edited")
+	errNoPrimaryKey  = errors.New("table has no primary key")
+	errRowNotChanged = errors.New("row was changed or deleted in the meantime")
+)
+
+// primaryKeyProvider is implemented by the dbInfo of drivers that can tell
+// the primary key of a table, which is required to edit the rows of a
+// result.
+type primaryKeyProvider interface {
+	// GetPrimaryKey returns the primary key columns of a table that may be
+	// qualified with its schema.
+	GetPrimaryKey(table string) ([]string, error)
+}
+
+// sourceTable returns the table that a statement selects from if the rows
+// of its result correspond to rows of that table, i.e. if it is a SELECT
+// from a single table without joins, grouping or set operations.
+func sourceTable(stmt sqlStatement, dialect sqlDialect) string {
+	if stmt.keyword() != "SELECT" {
+		return ""
+	}
+
+	for _, keyword := range []string{"JOIN", "GROUP", "DISTINCT", "UNION", "INTERSECT", "EXCEPT"} {
+		if stmt.hasKeyword(keyword) {
+			return ""
+		}
+	}
+
+	refs := referencedTables(stmt, dialect)
+	if len(refs) != 1 {
+		return ""
+	}
+
+	return refs[0].Name
+}
+
+// selectedColumns returns the items of the select list of stmt: the name of
+// the column for a plain, possibly qualified column reference, "*" for * and
+// t.*, and an empty string for expressions and aliased columns, whose values
+// can't be written back.
+func selectedColumns(stmt sqlStatement, dialect sqlDialect) []string {
+	var (
+		items   []string
+		item    []sqlToken
+		depth   int
+		started bool
+	)
+
+	for _, tok := range stmt.Tokens {
+		if tok.Kind == tokenWhitespace || tok.Kind == tokenComment {
+			continue
+		}
+
+		switch {
+		case !started: // SELECT
+			started = true
+
+			continue
+		case tok.Text == "(":
+			depth++
+		case tok.Text == ")":
+			depth--
+		case depth > 0:
+		case tok.Text == ",":
+			items = append(items, selectedColumn(item, dialect))
+			item = nil
+
+			continue
+		case tok.Kind == tokenWord && strings.EqualFold(tok.Text, "FROM"):
+			return append(items, selectedColumn(item, dialect))
+		}
+
+		item = append(item, tok)
+	}
+
+	return append(items, selectedColumn(item, dialect))
+}
+
+func selectedColumn(item []sqlToken, dialect sqlDialect) string {
+	// a column reference consists of identifiers separated by dots.
+	if len(item)%2 == 0 {
+		return ""
+	}
+
+	for idx, tok := range item {
+		switch {
+		case idx%2 == 1:
+			if tok.Text != "." {
+				return ""
+			}
+		case tok.Text == "*":
+			if idx == len(item)-1 {
+				return "*"
+			}
+
+			return ""
+		case tok.Kind != tokenQuotedIdent && (tok.Kind != tokenWord || reservedWords[strings.ToUpper(tok.Text)]):
+			return ""
+		}
+	}
+
+	return identifierName(item[len(item)-1], dialect)
+}
+
+// editStatement is a parameterized statement that applies a change.
+type editStatement struct {
+	Query     string // with ? placeholders
+	Args      []interface{}
+	SingleRow bool // statement must affect exactly one row
+}
+
+// preview returns the statement with its arguments as a comment.
+func (s editStatement) preview() string {
+	if len(s.Args) == 0 {
+		return s.Query + ";"
+	}
+
+	args := make([]string, 0, len(s.Args))
+	for _, arg := range s.Args {
+		args = append(args, sqlLiteral(arg))
+	}
+
+	return s.Query + "; -- " + strings.Join(args, ", ")
+}
+
+// rowEdit is a pending change of a row.
+type rowEdit struct {
+	values  []interface{} // values of the row including changes
+	changed []bool        // columns whose values were changed
+	deleted bool
+}
+
+// resultEdit holds the pending changes of a result whose rows are rows of
+// a table with a primary key.
+type resultEdit struct {
+	table    string
+	columns  []string
+	editable []bool // result columns that are columns of the table
+	key      []int  // indexes of the primary key columns
+
+	rows     map[int]*rowEdit // changes of fetched rows by index
+	inserted []*rowEdit
+}
+
+// newResultEdit checks that the result columns include the primary key of
+// the table and returns an empty edit. selected are the items of the select
+// list as returned by selectedColumns: only result columns that are plain
+// references to columns of the table can be edited.
+func newResultEdit(table string, columns, selected []string, tableColumns []column, primaryKey []string) (*resultEdit, error) {
+	if len(primaryKey) == 0 {
+		return nil, fmt.Errorf("%s: %w", table, errNoPrimaryKey)
+	}
+
+	e := &resultEdit{
+		table:    table,
+		columns:  columns,
+		editable: make([]bool, len(columns)),
+		rows:     make(map[int]*rowEdit),
+	}
+
+	var sources []string
+
+	for _, item := range selected {
+		if item != "*" {
+			sources = append(sources, item)
+
+			continue
+		}
+
+		for _, col := range tableColumns {
+			sources = append(sources, col.Name)
+		}
+	}
+
+	if len(sources) != len(columns) {
+		return nil, fmt.Errorf("result columns don't match the select list: %w", errNotEditable)
+	}
+
+	for idx, name := range columns {
+		if !strings.EqualFold(sources[idx], name) {
+			continue
+		}
+
+		for _, col := range tableColumns {
+			if col.Name == name {
+				e.editable[idx] = true
+			}
+		}
+	}
+
+	for _, keyColumn := range primaryKey {
+		found := false
+
+		for idx, name := range columns {
+			if name == keyColumn && e.editable[idx] {
+				e.key = append(e.key, idx)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("primary key column %s is missing from result: %w", keyColumn, errNotEditable)
+		}
+	}
+
+	return e, nil
+}
+
+// row returns the edit of a fetched row, creating it if necessary.
+func (e *resultEdit) row(idx int, original []interface{}) *rowEdit {
+	edit, ok := e.rows[idx]
+	if !ok {
+		edit = &rowEdit{
+			values:  append([]interface{}(nil), original...),
+			changed: make([]bool, len(original)),
+		}
+		e.rows[idx] = edit
+	}
+
+	return edit
+}
+
+func (e *resultEdit) insertRow() {
+	e.inserted = append(e.inserted, &rowEdit{
+		values:  make([]interface{}, len(e.columns)),
+		changed: make([]bool, len(e.columns)),
+	})
+}
+
+// pending returns the number of pending changes.
+func (e *resultEdit) pending() int {
+	n := len(e.inserted)
+
+	for _, edit := range e.rows {
+		if edit.deleted || edit.isChanged() {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (r *rowEdit) isChanged() bool {
+	for _, changed := range r.changed {
+		if changed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// statements returns the statements that apply the pending changes:
+// deletions first, then updates, then insertions. original returns a
+// fetched row as it was fetched.
+func (e *resultEdit) statements(quote func(string) string, original func(idx int) []interface{}) []editStatement {
+	indexes := make([]int, 0, len(e.rows))
+	for idx := range e.rows {
+		indexes = append(indexes, idx)
+	}
+
+	sort.Ints(indexes)
+
+	table := quoteTableName(quote, e.table)
+
+	var deletes, updates, inserts []editStatement
+
+	for _, idx := range indexes {
+		edit := e.rows[idx]
+		where, whereArgs := e.keyCondition(quote, original(idx))
+
+		switch {
+		case edit.deleted:
+			deletes = append(deletes, editStatement{
+				Query:     "DELETE FROM " + table + " WHERE " + where,
+				Args:      whereArgs,
+				SingleRow: true,
+			})
+		case edit.isChanged():
+			var (
+				assignments []string
+				args        []interface{}
+			)
+
+			for col, changed := range edit.changed {
+				if changed {
+					assignments = append(assignments, quote(e.columns[col])+" = ?")
+					args = append(args, edit.values[col])
+				}
+			}
+
+			updates = append(updates, editStatement{
+				Query:     "UPDATE " + table + " SET " + strings.Join(assignments, ", ") + " WHERE " + where,
+				Args:      append(args, whereArgs...),
+				SingleRow: true,
+			})
+		}
+	}
+
+	for _, edit := range e.inserted {
+		var (
+			columns, placeholders []string
+			args                  []interface{}
+		)
+
+		for col, changed := range edit.changed {
+			if changed {
+				columns = append(columns, quote(e.columns[col]))
+				placeholders = append(placeholders, "?")
+				args = append(args, edit.values[col])
+			}
+		}
+
+		stmt := editStatement{Query: "INSERT INTO " + table + " DEFAULT VALUES", SingleRow: true}
+		if len(columns) > 0 {
+			stmt.Query = "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+			stmt.Args = args
+		}
+
+		inserts = append(inserts, stmt)
+	}
+
+	return append(append(deletes, updates...), inserts...)
+}
+
+// keyCondition returns the condition that matches a row by its primary key.
+func (e *resultEdit) keyCondition(quote func(string) string, row []interface{}) (string, []interface{}) {
+	conds := make([]string, 0, len(e.key))
+	args := make([]interface{}, 0, len(e.key))
+
+	for _, col := range e.key {
+		conds = append(conds, quote(e.columns[col])+" = ?")
+		args = append(args, row[col])
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// apply applies the pending changes to rows, which are the rows that have
+// been fetched, and returns the resulting rows. Inserted rows are appended.
+func (e *resultEdit) apply(rows [][]interface{}) [][]interface{} {
+	result := make([][]interface{}, 0, len(rows)+len(e.inserted))
+
+	for idx, row := range rows {
+		edit, ok := e.rows[idx]
+
+		switch {
+		case !ok:
+			result = append(result, row)
+		case !edit.deleted:
+			result = append(result, edit.values)
+		}
+	}
+
+	for _, edit := range e.inserted {
+		result = append(result, edit.values)
+	}
+
+	e.rows = make(map[int]*rowEdit)
+	e.inserted = nil
+
+	return result
+}
+
+// quoteTableName quotes a table name that may be qualified with a schema.
+func quoteTableName(quote func(string) string, table string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return quote(schema) + "." + quote(name)
+	}
+
+	return quote(table)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSourceTable(t *testing.T) {
+	tests := []struct {
+		dialect sqlDialect
+		query   string
+		want    string
+	}{
+		{dialect: sqliteDialect, query: "SELECT * FROM users WHERE id = 1", want: "users"},
+		{dialect: sqliteDialect, query: "select id, name from public.users", want: "public.users"},
+		{dialect: sqliteDialect, query: "SELECT * FROM a JOIN b ON a.id = b.id"},
+		{dialect: sqliteDialect, query: "SELECT * FROM a, b"},
+		{dialect: sqliteDialect, query: "SELECT DISTINCT name FROM users"},
+		{dialect: sqliteDialect, query: "SELECT name, count(*) FROM users GROUP BY name"},
+		{dialect: sqliteDialect, query: "SELECT id FROM a UNION SELECT id FROM b"},
+		{dialect: sqliteDialect, query: "UPDATE users SET name = 'x'"},
+		{dialect: sqliteDialect, query: "SELECT * FROM Users", want: "Users"},
+		{dialect: postgresDialect, query: "SELECT * FROM Users", want: "users"},
+		{dialect: postgresDialect, query: `SELECT * FROM "Users"`, want: "Users"},
+		{dialect: postgresDialect, query: `SELECT * FROM Sales."Users"`, want: "sales.Users"},
+		{dialect: mysqlDialect, query: "SELECT * FROM `Users`", want: "Users"},
+	}
+
+	for _, tt := range tests {
+		if got := sourceTable(splitStatements(tt.query, tt.dialect)[0], tt.dialect); got != tt.want {
+			t.Errorf("sourceTable(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSelectedColumns(t *testing.T) {
+	tests := []struct {
+		dialect sqlDialect
+		query   string
+		want    []string
+	}{
+		{dialect: sqliteDialect, query: "SELECT * FROM t", want: []string{"*"}},
+		{dialect: sqliteDialect, query: "-- c\nSELECT t.*, id FROM t", want: []string{"*", "id"}},
+		{dialect: sqliteDialect, query: "SELECT id, t.name, main.t.note FROM t", want: []string{"id", "name", "note"}},
+		{dialect: sqliteDialect, query: "SELECT upper(name) AS name, name n, id + 1, 'x', count(*) FROM t", want: []string{"", "", "", "", ""}},
+		{dialect: sqliteDialect, query: "SELECT coalesce(a, b), c FROM t", want: []string{"", "c"}},
+		{dialect: postgresDialect, query: `SELECT Name, "Note" FROM t`, want: []string{"name", "Note"}},
+	}
+
+	for _, tt := range tests {
+		if got := selectedColumns(splitStatements(tt.query, tt.dialect)[0], tt.dialect); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("selectedColumns(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNewResultEdit(t *testing.T) {
+	tableColumns := []column{{Name: "id"}, {Name: "name"}}
+
+	tests := []struct {
+		name         string
+		query        string
+		columns      []string
+		primaryKey   []string
+		want         error
+		wantEditable []bool
+	}{
+		{name: "without primary key", query: "SELECT * FROM t", columns: []string{"id", "name"}, want: errNoPrimaryKey},
+		{name: "key column missing", query: "SELECT name FROM t", columns: []string{"name"}, primaryKey: []string{"id"}, want: errNotEditable},
+		{name: "key column is no table column", query: "SELECT name, id FROM t", columns: []string{"name", "id"}, primaryKey: []string{"name", "other"}, want: errNotEditable},
+		{name: "key column is derived", query: "SELECT id + 0 AS id, name FROM t", columns: []string{"id", "name"}, primaryKey: []string{"id"}, want: errNotEditable},
+		{name: "columns don't match select list", query: "SELECT * FROM t", columns: []string{"id"}, primaryKey: []string{"id"}, want: errNotEditable},
+		{name: "all columns", query: "SELECT * FROM t", columns: []string{"id", "name"}, primaryKey: []string{"id"}, wantEditable: []bool{true, true}},
+		{name: "expression", query: "SELECT name, id, upper(name) FROM t", columns: []string{"name", "id", "upper(name)"}, primaryKey: []string{"id"}, wantEditable: []bool{true, true, false}},
+		{name: "aliased expression", query: "SELECT upper(name) AS name, id FROM t", columns: []string{"name", "id"}, primaryKey: []string{"id"}, wantEditable: []bool{false, true}},
+		{name: "star and expression", query: "SELECT *, upper(name) AS name FROM t", columns: []string{"id", "name", "name"}, primaryKey: []string{"id"}, wantEditable: []bool{true, true, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := selectedColumns(splitStatements(tt.query, sqliteDialect)[0], sqliteDialect)
+
+			e, err := newResultEdit("t", tt.columns, selected, tableColumns, tt.primaryKey)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("newResultEdit returned error %v, want %v", err, tt.want)
+			}
+
+			if err == nil && !reflect.DeepEqual(e.editable, tt.wantEditable) {
+				t.Errorf("editable columns = %v, want %v", e.editable, tt.wantEditable)
+			}
+		})
+	}
+}
+
+func TestResultEditStatements(t *testing.T) {
+	quote := func(ident string) string { return `"` + ident + `"` }
+	rows := [][]interface{}{
+		{int64(1), "a", "x"},
+		{int64(2), "b", "y"},
+		{int64(3), "c", "z"},
+	}
+
+	e, err := newResultEdit("s.t", []string{"id", "name", "note"}, []string{"*"}, []column{{Name: "id"}, {Name: "name"}, {Name: "note"}}, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := e.row(2, rows[2])
+	update.values[1], update.changed[1] = "C", true
+	update.values[2], update.changed[2] = nil, true
+
+	e.row(0, rows[0]).deleted = true
+
+	// a row whose values were looked at but not changed doesn't count.
+	e.row(1, rows[1])
+
+	e.insertRow()
+	e.inserted[0].values[1], e.inserted[0].changed[1] = "d", true
+	e.insertRow()
+
+	if n := e.pending(); n != 4 {
+		t.Errorf("pending() = %d, want 4", n)
+	}
+
+	want := []editStatement{
+		{Query: `DELETE FROM "s"."t" WHERE "id" = ?`, Args: []interface{}{int64(1)}, SingleRow: true},
+		{Query: `UPDATE "s"."t" SET "name" = ?, "note" = ? WHERE "id" = ?`, Args: []interface{}{"C", nil, int64(3)}, SingleRow: true},
+		{Query: `INSERT INTO "s"."t" ("name") VALUES (?)`, Args: []interface{}{"d"}, SingleRow: true},
+		{Query: `INSERT INTO "s"."t" DEFAULT VALUES`, SingleRow: true},
+	}
+
+	got := e.statements(quote, func(idx int) []interface{} { return rows[idx] })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statements() = %#v, want %#v", got, want)
+	}
+
+	if preview := got[1].preview(); preview != `UPDATE "s"."t" SET "name" = ?, "note" = ? WHERE "id" = ?; -- 'C', NULL, 3` {
+		t.Errorf("preview() = %q", preview)
+	}
+
+	applied := e.apply(rows)
+	wantRows := [][]interface{}{
+		{int64(2), "b", "y"},
+		{int64(3), "C", nil},
+		{nil, "d", nil},
+		{nil, nil, nil},
+	}
+
+	if !reflect.DeepEqual(applied, wantRows) {
+		t.Errorf("apply() = %v, want %v", applied, wantRows)
+	}
+
+	if e.pending() != 0 {
+		t.Errorf("pending() = %d after apply, want 0", e.pending())
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/rivo/uniseg"
+)
+
+// syntaxColorsConfig configures the colors of the syntax highlighting in
+// the query editor. Colors are W3C color names or hex codes like #ff8000.
+type syntaxColorsConfig struct {
+	Keyword    string `yaml:"keyword"`
+	String     string `yaml:"string"`
+	Number     string `yaml:"number"`
+	Comment    string `yaml:"comment"`
+	Identifier string `yaml:"identifier"`
+}
+
+var defaultSyntaxColors = syntaxColorsConfig{
+	Keyword: "dodgerblue",
+	String:  "green",
+	Number:  "fuchsia",
+	Comment: "gray",
+}
+
+var errUnknownColor = errors.New("unknown color")
+
+// syntaxColors are the colors of the token types in the query editor.
+// tcell.ColorDefault keeps the text color.
+type syntaxColors struct {
+	Keyword    tcell.Color
+	String     tcell.Color
+	Number     tcell.Color
+	Comment    tcell.Color
+	Identifier tcell.Color
+}
+
+// syntaxColors returns the colors of cfg, using the default color for every
+// color that isn't set.
+func (cfg syntaxColorsConfig) syntaxColors() (syntaxColors, error) {
+	var colors syntaxColors
+
+	for _, c := range []struct {
+		Color   *tcell.Color
+		Name    string
+		Default string
+	}{
+		{&colors.Keyword, cfg.Keyword, defaultSyntaxColors.Keyword},
+		{&colors.String, cfg.String, defaultSyntaxColors.String},
+		{&colors.Number, cfg.Number, defaultSyntaxColors.Number},
+		{&colors.Comment, cfg.Comment, defaultSyntaxColors.Comment},
+		{&colors.Identifier, cfg.Identifier, defaultSyntaxColors.Identifier},
+	} {
+		name := c.Name
+		if name == "" {
+			name = c.Default
+		}
+
+		if name == "" {
+			continue
+		}
+
+		*c.Color = tcell.GetColor(name)
+		if *c.Color == tcell.ColorDefault {
+			return syntaxColors{}, fmt.Errorf("%s: %w", name, errUnknownColor)
+		}
+	}
+
+	return colors, nil
+}
+
+// color returns the color of tok.
+func (c syntaxColors) color(tok sqlToken) tcell.Color {
+	switch tok.Kind {
+	case tokenWord:
+		if reservedWords[strings.ToUpper(tok.Text)] {
+			return c.Keyword
+		}
+
+		return c.Identifier
+	case tokenQuotedIdent:
+		return c.Identifier
+	case tokenString:
+		return c.String
+	case tokenNumber:
+		return c.Number
+	case tokenComment:
+		return c.Comment
+	default:
+		return tcell.ColorDefault
+	}
+}
+
+// queryEditor is a text area for SQL queries with syntax highlighting. Lines
+// aren't wrapped, so that every line of the text corresponds to a row on the
+// screen.
+type queryEditor struct {
+	*tview.TextArea
+
+	textStyle tcell.Style
+	colors    syntaxColors
+	dialect   sqlDialect
+}
+
+func newQueryEditor() *queryEditor {
+	e := &queryEditor{
+		TextArea:  tview.NewTextArea(),
+		textStyle: tcell.StyleDefault.Background(tview.Styles.PrimitiveBackgroundColor).Foreground(tview.Styles.PrimaryTextColor),
+		dialect:   genericDialect,
+	}
+
+	e.SetWrap(false)
+	e.SetTextStyle(e.textStyle)
+
+	return e
+}
+
+func (e *queryEditor) setColors(colors syntaxColors) {
+	e.colors = colors
+}
+
+// setDialect sets the SQL dialect that is used to tokenize the text.
+func (e *queryEditor) setDialect(dialect sqlDialect) {
+	e.dialect = dialect
+}
+
+// Draw draws the text area and then colors the visible text according to
+// its tokens. Selected text keeps the selection style.
+func (e *queryEditor) Draw(screen tcell.Screen) {
+	e.TextArea.Draw(screen)
+
+	text := e.GetText()
+	if text == "" {
+		return
+	}
+
+	x, y, width, height := e.GetInnerRect()
+	rowOffset, columnOffset := e.GetOffset()
+	tokens := tokenizeDialect(text, e.dialect)
+
+	var (
+		pos, row, column int
+		tokIdx           int
+		boundaries       int
+		cluster          string
+	)
+
+	rest := text
+	state := -1
+
+	for rest != "" && row-rowOffset < height {
+		cluster, rest, boundaries, state = uniseg.StepString(rest, state)
+
+		for tokIdx < len(tokens)-1 && pos >= tokens[tokIdx].End {
+			tokIdx++
+		}
+
+		clusterWidth := boundaries >> uniseg.ShiftWidth
+		if cluster == "\t" {
+			clusterWidth = tview.TabSize
+		}
+
+		screenX, screenY := x+column-columnOffset, y+row-rowOffset
+		if color := e.colors.color(tokens[tokIdx]); color != tcell.ColorDefault && row >= rowOffset && column >= columnOffset && column+clusterWidth-columnOffset <= width && clusterWidth > 0 {
+			mainc, combc, style, _ := screen.GetContent(screenX, screenY)
+			if style == e.textStyle {
+				screen.SetContent(screenX, screenY, mainc, combc, style.Foreground(color))
+			}
+		}
+
+		pos += len(cluster)
+		column += clusterWidth
+
+		if boundaries&uniseg.MaskLine == uniseg.LineMustBreak && (rest != "" || uniseg.HasTrailingLineBreakInString(cluster)) {
+			row++
+			column = 0
+		}
+	}
+}
+
+// MouseHandler makes sure that the editor rather than the embedded text
+// area gets the focus when it is clicked.
+func (e *queryEditor) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+	handler := e.TextArea.MouseHandler()
+
+	return func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+		consumed, capture := handler(action, event, func(p tview.Primitive) {
+			setFocus(e)
+		})
+
+		if capture == e.TextArea {
+			capture = e
+		}
+
+		return consumed, capture
+	}
+}
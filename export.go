@@ -0,0 +1,454 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// resultWriter writes a result in a particular format. Rows are written one
+// by one, so that large results don't need to be held in memory.
+type resultWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+type exportOptions struct {
+	TableName  string                    // name of table for SQL INSERT statements
+	QuoteIdent func(ident string) string // quotes identifiers for SQL INSERT statements
+}
+
+type exportFormat struct {
+	Name      string
+	Extension string
+	NewWriter func(w io.Writer, opts exportOptions) resultWriter
+}
+
+var exportFormats = []exportFormat{
+	{Name: "CSV", Extension: "csv", NewWriter: func(w io.Writer, opts exportOptions) resultWriter {
+		return newCSVResultWriter(w, ',')
+	}},
+	{Name: "TSV", Extension: "tsv", NewWriter: func(w io.Writer, opts exportOptions) resultWriter {
+		return newCSVResultWriter(w, '\t')
+	}},
+	{Name: "JSON", Extension: "json", NewWriter: func(w io.Writer, opts exportOptions) resultWriter {
+		return &jsonResultWriter{w: w}
+	}},
+	{Name: "NDJSON", Extension: "ndjson", NewWriter: func(w io.Writer, opts exportOptions) resultWriter {
+		return &jsonResultWriter{w: w, ndjson: true}
+	}},
+	{Name: "Markdown", Extension: "md", NewWriter: func(w io.Writer, opts exportOptions) resultWriter {
+		return &markdownResultWriter{w: w}
+	}},
+	{Name: "SQL", Extension: "sql", NewWriter: func(w io.Writer, opts exportOptions) resultWriter {
+		return &sqlResultWriter{w: w, opts: opts}
+	}},
+	{Name: "XLSX", Extension: "xlsx", NewWriter: func(w io.Writer, opts exportOptions) resultWriter {
+		return &xlsxResultWriter{zw: zip.NewWriter(w)}
+	}},
+}
+
+func exportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for _, format := range exportFormats {
+		names = append(names, format.Name)
+	}
+
+	return names
+}
+
+func getExportFormat(name string) (exportFormat, bool) {
+	for _, format := range exportFormats {
+		if strings.EqualFold(format.Name, name) {
+			return format, true
+		}
+	}
+
+	return exportFormat{}, false
+}
+
+type csvResultWriter struct {
+	w *csv.Writer
+}
+
+func newCSVResultWriter(w io.Writer, comma rune) *csvResultWriter {
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = comma
+
+	return &csvResultWriter{w: csvWriter}
+}
+
+func (w *csvResultWriter) WriteHeader(columns []string) error {
+	return w.w.Write(columns)
+}
+
+func (w *csvResultWriter) WriteRow(values []interface{}) error {
+	fields := make([]string, 0, len(values))
+	for _, v := range values {
+		fields = append(fields, valueString(v, ""))
+	}
+
+	return w.w.Write(fields)
+}
+
+func (w *csvResultWriter) Close() error {
+	w.w.Flush()
+
+	return w.w.Error()
+}
+
+// jsonResultWriter writes rows as JSON objects, either as a JSON array or
+// as newline-delimited JSON.
+type jsonResultWriter struct {
+	w       io.Writer
+	ndjson  bool
+	columns []string
+	rows    int
+}
+
+func (w *jsonResultWriter) WriteHeader(columns []string) error {
+	w.columns = columns
+
+	if w.ndjson {
+		return nil
+	}
+
+	_, err := io.WriteString(w.w, "[")
+
+	return err
+}
+
+func (w *jsonResultWriter) WriteRow(values []interface{}) error {
+	data, err := marshalJSONRow(w.columns, values)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case w.ndjson:
+		_, err = fmt.Fprintf(w.w, "%s\n", data)
+	case w.rows > 0:
+		_, err = fmt.Fprintf(w.w, ",\n  %s", data)
+	default:
+		_, err = fmt.Fprintf(w.w, "\n  %s", data)
+	}
+
+	w.rows++
+
+	return err
+}
+
+func (w *jsonResultWriter) Close() error {
+	if w.ndjson {
+		return nil
+	}
+
+	_, err := io.WriteString(w.w, "\n]\n")
+
+	return err
+}
+
+// marshalJSONRow marshals a row as JSON object, keeping the order of the
+// columns. NaN and infinite numbers, which JSON can't represent, are written
+// as null.
+func marshalJSONRow(columns []string, values []interface{}) ([]byte, error) {
+	var buf strings.Builder
+
+	buf.WriteByte('{')
+
+	for idx, value := range values {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(columns[idx])
+		if err != nil {
+			return nil, fmt.Errorf("marshalling column name failed: %w", err)
+		}
+
+		if isNonFinite(value) {
+			value = nil
+		}
+
+		val, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling value failed: %w", err)
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+
+	buf.WriteByte('}')
+
+	return []byte(buf.String()), nil
+}
+
+type markdownResultWriter struct {
+	w io.Writer
+}
+
+func (w *markdownResultWriter) WriteHeader(columns []string) error {
+	if err := w.writeLine(columns); err != nil {
+		return err
+	}
+
+	separators := make([]string, len(columns))
+	for idx := range separators {
+		separators[idx] = "---"
+	}
+
+	return w.writeLine(separators)
+}
+
+func (w *markdownResultWriter) WriteRow(values []interface{}) error {
+	fields := make([]string, 0, len(values))
+	for _, v := range values {
+		fields = append(fields, valueString(v, "NULL"))
+	}
+
+	return w.writeLine(fields)
+}
+
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+func (w *markdownResultWriter) writeLine(fields []string) error {
+	escaped := make([]string, 0, len(fields))
+	for _, field := range fields {
+		escaped = append(escaped, markdownEscaper.Replace(field))
+	}
+
+	_, err := fmt.Fprintf(w.w, "| %s |\n", strings.Join(escaped, " | "))
+
+	return err
+}
+
+func (w *markdownResultWriter) Close() error {
+	return nil
+}
+
+var errNoTableName = errors.New("no table name for SQL INSERT statements")
+
+// sqlResultWriter writes every row as SQL INSERT statement.
+type sqlResultWriter struct {
+	w      io.Writer
+	opts   exportOptions
+	prefix string
+}
+
+func (w *sqlResultWriter) WriteHeader(columns []string) error {
+	if w.opts.TableName == "" {
+		return errNoTableName
+	}
+
+	quoted := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quoted = append(quoted, w.opts.QuoteIdent(col))
+	}
+
+	w.prefix = fmt.Sprintf("INSERT INTO %s (%s) VALUES (", quoteTableName(w.opts.QuoteIdent, w.opts.TableName), strings.Join(quoted, ", "))
+
+	return nil
+}
+
+func (w *sqlResultWriter) WriteRow(values []interface{}) error {
+	literals := make([]string, 0, len(values))
+	for _, v := range values {
+		literals = append(literals, sqlLiteral(v))
+	}
+
+	_, err := fmt.Fprintf(w.w, "%s%s);\n", w.prefix, strings.Join(literals, ", "))
+
+	return err
+}
+
+func (w *sqlResultWriter) Close() error {
+	return nil
+}
+
+// sqlLiteral converts a normalized value into an SQL literal. NaN and
+// infinite numbers have no literal and become NULL.
+func sqlLiteral(v interface{}) string {
+	if isNonFinite(v) {
+		return "NULL"
+	}
+
+	switch value := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if value {
+			return "TRUE"
+		}
+
+		return "FALSE"
+	case int64, float64, json.Number:
+		return fmt.Sprint(value)
+	case []byte:
+		return fmt.Sprintf("X'%x'", value)
+	case time.Time:
+		return "'" + value.Format(timeLayout) + "'"
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(value), "'", "''") + "'"
+	}
+}
+
+// xlsxResultWriter writes a minimal Office Open XML workbook with a single
+// worksheet. Numbers are written as numeric cells, everything else as
+// inline strings.
+type xlsxResultWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+var xlsxStaticFiles = []struct {
+	Name    string
+	Content string
+}{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Result" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func (w *xlsxResultWriter) WriteHeader(columns []string) error {
+	for _, f := range xlsxStaticFiles {
+		fw, err := w.zw.Create(f.Name)
+		if err != nil {
+			return fmt.Errorf("creating %s failed: %w", f.Name, err)
+		}
+
+		if _, err := io.WriteString(fw, f.Content); err != nil {
+			return fmt.Errorf("writing %s failed: %w", f.Name, err)
+		}
+	}
+
+	sheet, err := w.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return fmt.Errorf("creating worksheet failed: %w", err)
+	}
+
+	w.sheet = sheet
+
+	if _, err := io.WriteString(w.sheet, xml.Header+
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return fmt.Errorf("writing worksheet failed: %w", err)
+	}
+
+	values := make([]interface{}, 0, len(columns))
+	for _, col := range columns {
+		values = append(values, col)
+	}
+
+	return w.WriteRow(values)
+}
+
+func (w *xlsxResultWriter) WriteRow(values []interface{}) error {
+	w.row++
+
+	var buf strings.Builder
+
+	fmt.Fprintf(&buf, `<row r="%d">`, w.row)
+
+	for _, v := range values {
+		// NaN and infinite numbers are no valid cell values, so they are
+		// written as text.
+		if isNonFinite(v) {
+			v = valueString(v, "")
+		}
+
+		switch value := v.(type) {
+		case nil:
+			buf.WriteString(`<c/>`)
+		case int64, float64, json.Number:
+			fmt.Fprintf(&buf, `<c><v>%v</v></c>`, value)
+		default:
+			buf.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+
+			if err := xml.EscapeText(&buf, []byte(valueString(value, ""))); err != nil {
+				return fmt.Errorf("escaping value failed: %w", err)
+			}
+
+			buf.WriteString(`</t></is></c>`)
+		}
+	}
+
+	buf.WriteString(`</row>`)
+
+	if _, err := io.WriteString(w.sheet, buf.String()); err != nil {
+		return fmt.Errorf("writing worksheet failed: %w", err)
+	}
+
+	return nil
+}
+
+func (w *xlsxResultWriter) Close() error {
+	if w.sheet != nil {
+		if _, err := io.WriteString(w.sheet, `</sheetData></worksheet>`); err != nil {
+			return fmt.Errorf("writing worksheet failed: %w", err)
+		}
+	}
+
+	if err := w.zw.Close(); err != nil {
+		return fmt.Errorf("finishing XLSX file failed: %w", err)
+	}
+
+	return nil
+}
+
+// isNonFinite returns whether v is a NaN or infinite floating-point number.
+func isNonFinite(v interface{}) bool {
+	f, ok := v.(float64)
+
+	return ok && (math.IsNaN(f) || math.IsInf(f, 0))
+}
+
+// exportResult writes columns and rows to the file filename in the given
+// format.
+func exportResult(filename string, format exportFormat, opts exportOptions, columns []string, rows [][]interface{}) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("opening %s failed: %w", filename, err)
+	}
+	defer f.Close()
+
+	w := format.NewWriter(f, opts)
+
+	if err := w.WriteHeader(columns); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
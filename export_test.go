@@ -0,0 +1,188 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestResultWriters(t *testing.T) {
+	columns := []string{"id", "name", "data"}
+	rows := [][]interface{}{
+		{int64(1), "a|b", []byte{0x01}},
+		{json.Number("2.5"), "it's\nx", nil},
+	}
+
+	opts := exportOptions{
+		TableName:  "s.My Table",
+		QuoteIdent: func(ident string) string { return `"` + ident + `"` },
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "CSV",
+			want:   "id,name,data\n1,a|b,\\x01\n2.5,\"it's\nx\",\n",
+		},
+		{
+			format: "TSV",
+			want:   "id\tname\tdata\n1\ta|b\t\\x01\n2.5\t\"it's\nx\"\t\n",
+		},
+		{
+			format: "JSON",
+			want:   "[\n  {\"id\":1,\"name\":\"a|b\",\"data\":\"AQ==\"},\n  {\"id\":2.5,\"name\":\"it's\\nx\",\"data\":null}\n]\n",
+		},
+		{
+			format: "NDJSON",
+			want:   "{\"id\":1,\"name\":\"a|b\",\"data\":\"AQ==\"}\n{\"id\":2.5,\"name\":\"it's\\nx\",\"data\":null}\n",
+		},
+		{
+			format: "Markdown",
+			want:   "| id | name | data |\n| --- | --- | --- |\n| 1 | a\\|b | \\x01 |\n| 2.5 | it's<br>x | NULL |\n",
+		},
+		{
+			format: "SQL",
+			want: "INSERT INTO \"s\".\"My Table\" (\"id\", \"name\", \"data\") VALUES (1, 'a|b', X'01');\n" +
+				"INSERT INTO \"s\".\"My Table\" (\"id\", \"name\", \"data\") VALUES (2.5, 'it''s\nx', NULL);\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			format, ok := getExportFormat(tt.format)
+			if !ok {
+				t.Fatalf("format %s not found", tt.format)
+			}
+
+			var buf bytes.Buffer
+
+			if err := writeTestResult(format.NewWriter(&buf, opts), columns, rows); err != nil {
+				t.Fatalf("writing result returned error: %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("%s export = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNonFiniteNumbers(t *testing.T) {
+	opts := exportOptions{
+		TableName:  "t",
+		QuoteIdent: func(ident string) string { return ident },
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "CSV", want: "a,b,c\nNaN,-Inf,1.5\n"},
+		{format: "NDJSON", want: `{"a":null,"b":null,"c":1.5}` + "\n"},
+		{format: "SQL", want: "INSERT INTO t (a, b, c) VALUES (NULL, NULL, 1.5);\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			format, _ := getExportFormat(tt.format)
+
+			var buf bytes.Buffer
+
+			if err := writeTestResult(format.NewWriter(&buf, opts), []string{"a", "b", "c"}, [][]interface{}{
+				{math.NaN(), math.Inf(-1), 1.5},
+			}); err != nil {
+				t.Fatalf("writing result returned error: %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("%s export = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXLSXResultWriter(t *testing.T) {
+	format, _ := getExportFormat("xlsx")
+
+	var buf bytes.Buffer
+
+	if err := writeTestResult(format.NewWriter(&buf, exportOptions{}), []string{"n", "s"}, [][]interface{}{
+		{int64(42), "<a & b>"},
+		{nil, []byte{0xff}},
+		{math.Inf(1), 0.5},
+	}); err != nil {
+		t.Fatalf("writing result returned error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("reading XLSX file failed: %v", err)
+	}
+
+	files := make(map[string]string)
+
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(r)
+		r.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files[f.Name] = string(data)
+	}
+
+	for _, f := range xlsxStaticFiles {
+		if files[f.Name] != f.Content {
+			t.Errorf("%s has content %q", f.Name, files[f.Name])
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+
+	for _, want := range []string{
+		`<row r="1"><c t="inlineStr"><is><t xml:space="preserve">n</t></is></c><c t="inlineStr"><is><t xml:space="preserve">s</t></is></c></row>`,
+		`<row r="2"><c><v>42</v></c><c t="inlineStr"><is><t xml:space="preserve">&lt;a &amp; b&gt;</t></is></c></row>`,
+		`<row r="3"><c/><c t="inlineStr"><is><t xml:space="preserve">\xff</t></is></c></row>`,
+		`<row r="4"><c t="inlineStr"><is><t xml:space="preserve">+Inf</t></is></c><c><v>0.5</v></c></row>`,
+		`</sheetData></worksheet>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("worksheet %q doesn't contain %q", sheet, want)
+		}
+	}
+}
+
+func TestSQLResultWriterWithoutTableName(t *testing.T) {
+	format, _ := getExportFormat("SQL")
+
+	if err := format.NewWriter(io.Discard, exportOptions{}).WriteHeader([]string{"a"}); !errors.Is(err, errNoTableName) {
+		t.Errorf("WriteHeader returned error %v, want %v", err, errNoTableName)
+	}
+}
+
+func writeTestResult(w resultWriter, columns []string, rows [][]interface{}) error {
+	if err := w.WriteHeader(columns); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// queryHistory records all executed queries in an SQLite database.
+type queryHistory struct {
+	db *sqlx.DB
+}
+
+type historyEntry struct {
+	ID         int64     `db:"id"`
+	ExecutedAt time.Time `db:"executed_at"`
+	Database   string    `db:"db"`
+	Connection string    `db:"connection"`
+	Query      string    `db:"query"`
+	DurationMS int64     `db:"duration_ms"`
+	RowCount   int64     `db:"row_count"`
+	Error      string    `db:"error"`
+}
+
+const historySchema = `
+	CREATE TABLE IF NOT EXISTS history (
+		id INTEGER PRIMARY KEY,
+		executed_at TIMESTAMP NOT NULL,
+		db TEXT NOT NULL,
+		connection TEXT NOT NULL,
+		query TEXT NOT NULL,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		row_count INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT ''
+	)`
+
+func openQueryHistory(filename string) (*queryHistory, error) {
+	db, err := sqlx.Open("sqlite", filename)
+	if err != nil {
+		return nil, fmt.Errorf("opening history file %s failed: %w", filename, err)
+	}
+
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+
+		return nil, fmt.Errorf("creating history table failed: %w", err)
+	}
+
+	return &queryHistory{db: db}, nil
+}
+
+// add records the start of a query execution on the database dbName,
+// identified by connection, and returns the ID of the new history entry.
+func (h *queryHistory) add(executedAt time.Time, dbName, connection, query string) (int64, error) {
+	res, err := h.db.Exec("INSERT INTO history (executed_at, db, connection, query) VALUES (?, ?, ?, ?)", executedAt.UTC().Round(0), dbName, connection, query)
+	if err != nil {
+		return 0, fmt.Errorf("adding history entry failed: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("adding history entry failed: %w", err)
+	}
+
+	return id, nil
+}
+
+// finish records the outcome of a query execution.
+func (h *queryHistory) finish(id int64, duration time.Duration, execErr error) error {
+	errMsg := ""
+	if execErr != nil {
+		errMsg = execErr.Error()
+	}
+
+	if _, err := h.db.Exec("UPDATE history SET duration_ms = ?, error = ? WHERE id = ?", duration.Milliseconds(), errMsg, id); err != nil {
+		return fmt.Errorf("updating history entry failed: %w", err)
+	}
+
+	return nil
+}
+
+// addRows adds n to the number of rows that a query execution returned or
+// affected.
+func (h *queryHistory) addRows(id int64, n int64) error {
+	if _, err := h.db.Exec("UPDATE history SET row_count = row_count + ? WHERE id = ?", n, id); err != nil {
+		return fmt.Errorf("updating history entry failed: %w", err)
+	}
+
+	return nil
+}
+
+// recent returns the last limit history entries of the database identified
+// by connection, or of all databases if connection is empty, most recent
+// first.
+func (h *queryHistory) recent(limit int, connection string) ([]historyEntry, error) {
+	var entries []historyEntry
+
+	if err := h.db.Select(&entries, "SELECT * FROM history WHERE ? = '' OR connection = ? ORDER BY id DESC LIMIT ?", connection, connection, limit); err != nil {
+		return nil, fmt.Errorf("listing history failed: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (h *queryHistory) close() error {
+	return h.db.Close()
+}
+
+// fuzzyMatch returns whether all characters of pattern appear in text in
+// the same order, ignoring case. The score is higher the more of the
+// matched characters are adjacent.
+func fuzzyMatch(pattern, text string) (score int, ok bool) {
+	patternRunes := []rune(strings.ToLower(pattern))
+	if len(patternRunes) == 0 {
+		return 0, true
+	}
+
+	idx := 0
+	prevMatched := false
+
+	for _, r := range strings.ToLower(text) {
+		if r != patternRunes[idx] {
+			prevMatched = false
+
+			continue
+		}
+
+		score++
+		if prevMatched {
+			score += 2
+		}
+
+		prevMatched = !unicode.IsSpace(r)
+
+		idx++
+		if idx == len(patternRunes) {
+			return score, true
+		}
+	}
+
+	return 0, false
+}
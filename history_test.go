@@ -0,0 +1,98 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		text    string
+		wantOK  bool
+	}{
+		{pattern: "", text: "SELECT 1", wantOK: true},
+		{pattern: "sel", text: "SELECT 1", wantOK: true},
+		{pattern: "sfu", text: "select * from users", wantOK: true},
+		{pattern: "usr", text: "select * from users", wantOK: true},
+		{pattern: "xyz", text: "select * from users", wantOK: false},
+		{pattern: "fs", text: "select * from users", wantOK: true},
+		{pattern: "sss", text: "select", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := fuzzyMatch(tt.pattern, tt.text); ok != tt.wantOK {
+			t.Errorf("fuzzyMatch(%q, %q) = %t, want %t", tt.pattern, tt.text, ok, tt.wantOK)
+		}
+	}
+
+	adjacent, _ := fuzzyMatch("from", "select * from users")
+	scattered, _ := fuzzyMatch("from", "f r o m")
+
+	if adjacent <= scattered {
+		t.Errorf("adjacent match scored %d, not more than scattered match with %d", adjacent, scattered)
+	}
+}
+
+func TestQueryHistory(t *testing.T) {
+	history, err := openQueryHistory(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.close()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// both databases have the same name, but different connections.
+	for idx, e := range []struct{ connection, query string }{
+		{"sqlite;file=/a/x.db", "SELECT 1"},
+		{"sqlite;file=/b/x.db", "SELECT 2"},
+		{"sqlite;file=/a/x.db", "SELECT 3"},
+	} {
+		id, err := history.add(start.Add(time.Duration(idx)*time.Second), "x.db", e.connection, e.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := history.addRows(id, 2); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := history.finish(id, time.Second, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		connection string
+		limit      int
+		want       []string
+	}{
+		{connection: "", limit: 10, want: []string{"SELECT 3", "SELECT 2", "SELECT 1"}},
+		{connection: "sqlite;file=/a/x.db", limit: 10, want: []string{"SELECT 3", "SELECT 1"}},
+		{connection: "sqlite;file=/b/x.db", limit: 10, want: []string{"SELECT 2"}},
+		{connection: "", limit: 1, want: []string{"SELECT 3"}},
+	}
+
+	for _, tt := range tests {
+		entries, err := history.recent(tt.limit, tt.connection)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var queries []string
+		for _, entry := range entries {
+			queries = append(queries, entry.Query)
+
+			if entry.RowCount != 2 || entry.DurationMS != 1000 {
+				t.Errorf("entry %q has %d rows and %d ms, want 2 rows and 1000 ms", entry.Query, entry.RowCount, entry.DurationMS)
+			}
+		}
+
+		if !reflect.DeepEqual(queries, tt.want) {
+			t.Errorf("recent(%d, %q) = %q, want %q", tt.limit, tt.connection, queries, tt.want)
+		}
+	}
+}
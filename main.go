@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"log"
 	"os"
@@ -23,6 +26,19 @@ type config struct {
 		Key       string `yaml:"key"`
 		Operation string `yaml:"operation"`
 	} `yaml:"keys"`
+	PageSize     int                 `yaml:"page_size"`
+	TimeFormat   string              `yaml:"time_format"` // Go time layout for timestamps in result table
+	SyntaxColors syntaxColorsConfig  `yaml:"syntax_colors"`
+	Connections  []connectionProfile `yaml:"connections"`
+	Credentials  credentialsConfig   `yaml:"credentials"`
+	Clipboard    clipboardConfig     `yaml:"clipboard"`
+	SnippetDir   string              `yaml:"snippet_dir"` // directory of .sql files shown as snippets
+}
+
+type connectionProfile struct {
+	Name          string            `yaml:"name"`
+	Driver        string            `yaml:"driver"`
+	ConnectParams map[string]string `yaml:"connect_params"`
 }
 
 func loadConfig(filename string) (config, error) {
@@ -33,18 +49,82 @@ func loadConfig(filename string) (config, error) {
 		return cfg, fmt.Errorf("couldn't read configuration file %s: %w", filename, err)
 	}
 
-	if err := yaml.Unmarshal(configData, cfg); err != nil {
+	if err := yaml.Unmarshal(configData, &cfg); err != nil {
 		return cfg, fmt.Errorf("couldn't unmarshal configuration file %s: %w", filename, err)
 	}
 
 	return cfg, nil
 }
 
+// storeConnectionProfiles replaces the connections section of the
+// configuration file with profiles. All other content of the file is kept
+// as is.
+func storeConnectionProfiles(filename string, profiles []connectionProfile) error {
+	configData, err := ioutil.ReadFile(filename)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("couldn't read configuration file %s: %w", filename, err)
+	}
+
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(configData, &doc); err != nil {
+		return fmt.Errorf("couldn't unmarshal configuration file %s: %w", filename, err)
+	}
+
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("configuration file %s: %w", filename, errInvalidConfig)
+	}
+
+	var profilesNode yaml.Node
+	if err := profilesNode.Encode(profiles); err != nil {
+		return fmt.Errorf("marshalling connection profiles failed: %w", err)
+	}
+
+	found := false
+
+	for idx := 0; idx+1 < len(root.Content); idx += 2 {
+		if root.Content[idx].Value == "connections" {
+			root.Content[idx+1] = &profilesNode
+			found = true
+		}
+	}
+
+	if !found {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "connections"}, &profilesNode)
+	}
+
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("marshalling configuration failed: %w", err)
+	}
+
+	if err := ioutil.WriteFile(filename, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("storing configuration to %s failed: %w", filename, err)
+	}
+
+	return nil
+}
+
+var errInvalidConfig = errors.New("configuration is not a mapping")
+
 func main() {
 	var (
 		debugLogFile string
 		configFile   string
 		sessionFile  string
+		dbName       string
+		query        string
+		format       string
+		tableName    string
 	)
 
 	configDir := filepath.Join(os.Getenv("HOME"), ".config", "koios")
@@ -52,8 +132,23 @@ func main() {
 	flag.StringVar(&debugLogFile, "debuglog", "", "debug log file")
 	flag.StringVar(&configFile, "configfile", filepath.Join(configDir, "config.yml"), "configuration file")
 	flag.StringVar(&sessionFile, "statefile", filepath.Join(configDir, "session.yml"), "session file")
+	flag.StringVar(&dbName, "db", "", "name of database from session to run query on without starting the user interface")
+	flag.StringVar(&query, "e", "", "query to run when -db is set; read from stdin if empty")
+	flag.StringVar(&format, "format", "table", "output format when -db is set (table, csv, tsv, json, ndjson, markdown, sql, xlsx)")
+	flag.StringVar(&tableName, "table", "", "table name for INSERT statements when -format is sql")
 	flag.Parse()
 
+	if dbName == "" {
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "e", "format", "table":
+				fmt.Fprintf(os.Stderr, "-%s can only be used together with -db\n", f.Name)
+				flag.Usage()
+				os.Exit(2)
+			}
+		})
+	}
+
 	if debugLogFile != "" {
 		f, err := os.OpenFile(debugLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
 		if err != nil {
@@ -70,17 +165,50 @@ func main() {
 		log.Printf("Couldn't create config directory %s: %v", configDir, err)
 	}
 
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Printf("Loading configuration failed: %v", err)
+	}
+
 	model := newModel()
+	model.setProfiles(configFile, cfg.Connections)
+
+	if !cfg.Credentials.keepInSession() {
+		store, err := newCredentialStore(cfg.Credentials, filepath.Join(configDir, "credentials.enc"), readPassphrase)
+		if err != nil {
+			fail("Opening credential store failed: %v\n", err)
+		}
+
+		model.setCredentialStore(store)
+	}
+
+	history, err := openQueryHistory(filepath.Join(configDir, "history.db"))
+	if err != nil {
+		log.Printf("Opening query history failed: %v", err)
+	} else {
+		defer history.close()
+		model.setHistory(history)
+	}
+
+	if cfg.SnippetDir == "" {
+		cfg.SnippetDir = filepath.Join(configDir, "snippets")
+	}
+
+	model.setSnippetLibrary(newSnippetLibrary(cfg.SnippetDir))
+
+	if dbName != "" {
+		if err := runHeadless(model, sessionFile, dbName, query, format, tableName); err != nil {
+			fail("%v\n", err)
+		}
+
+		return
+	}
+
 	view := newMainView()
 	ctrl := newController(model, view)
 	view.setController(ctrl)
 	model.setController(ctrl)
 
-	cfg, err := loadConfig(configFile)
-	if err != nil {
-		log.Printf("Loading configuration failed: %v", err)
-	}
-
 	if err := view.configure(cfg); err != nil {
 		fail("Configuration failed: %v", err)
 	}
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	_ "github.com/akrennmair/go-athena"
 	"github.com/jmoiron/sqlx"
@@ -15,13 +22,29 @@ type model struct {
 	ctrl    *controller
 	dbInfo  map[string]dbInfo
 	counter int
+
+	txMtx sync.Mutex
+	tx    map[string]*sqlx.Tx // open transactions by dbID
+
+	configFile   string
+	profiles     []connectionProfile
+	profileNames map[string]string // names of profiles that databases were opened from by dbID
+
+	credStore credentialStore // nil if secrets are kept in session and configuration
+	history   *queryHistory   // nil if no history is kept
+	snippets  *snippetLibrary // nil if there is no snippet library
+
+	schema *schemaCache // tables and columns for completions
 }
 
 type connectParams map[string]string
 
 func newModel() *model {
 	return &model{
-		dbInfo: make(map[string]dbInfo),
+		dbInfo:       make(map[string]dbInfo),
+		tx:           make(map[string]*sqlx.Tx),
+		profileNames: make(map[string]string),
+		schema:       newSchemaCache(),
 	}
 }
 
@@ -29,17 +52,106 @@ func (m *model) setController(c *controller) {
 	m.ctrl = c
 }
 
+func (m *model) setProfiles(configFile string, profiles []connectionProfile) {
+	m.configFile = configFile
+	m.profiles = profiles
+}
+
+func (m *model) setCredentialStore(store credentialStore) {
+	m.credStore = store
+}
+
+func (m *model) getProfiles() []connectionProfile {
+	return m.profiles
+}
+
+func (m *model) getProfile(name string) (connectionProfile, bool) {
+	for _, profile := range m.profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+
+	return connectionProfile{}, false
+}
+
+// checkSecrets returns an error if the credential store can't keep the
+// secrets in params, which would then be missing when the session is
+// restored.
+func (m *model) checkSecrets(driver string, params connectParams) error {
+	if _, ok := m.credStore.(externalCredentialStore); !ok {
+		return nil
+	}
+
+	_, err := stripSecrets(m.credStore, driver, params)
+
+	return err
+}
+
+// saveProfile adds profile to the list of connection profiles, replacing
+// any existing profile of the same name, and stores the list in the
+// configuration file.
+func (m *model) saveProfile(profile connectionProfile) error {
+	params, err := stripSecrets(m.credStore, profile.Driver, profile.ConnectParams)
+	if err != nil {
+		return fmt.Errorf("storing secrets of profile %s failed: %w", profile.Name, err)
+	}
+
+	profile.ConnectParams = params
+
+	profiles := make([]connectionProfile, 0, len(m.profiles)+1)
+	replaced := false
+
+	for _, p := range m.profiles {
+		if p.Name == profile.Name {
+			p = profile
+			replaced = true
+		}
+
+		profiles = append(profiles, p)
+	}
+
+	if !replaced {
+		profiles = append(profiles, profile)
+	}
+
+	if err := storeConnectionProfiles(m.configFile, profiles); err != nil {
+		return err
+	}
+
+	m.profiles = profiles
+
+	return nil
+}
+
 type column struct {
 	Name string
 	Type string
 }
 
 var (
-	errDatabaseNotOpen   = errors.New("database is not open")
-	errUnsupportedDriver = errors.New("unsupported driver")
+	errDatabaseNotOpen    = errors.New("database is not open")
+	errUnsupportedDriver  = errors.New("unsupported driver")
+	errEmptyQuery         = errors.New("query is empty")
+	errTxAlreadyOpen      = errors.New("transaction is already open")
+	errNoTxOpen           = errors.New("no transaction is open")
+	errNoHistory          = errors.New("query history is not available")
+	errExplainUnsupported = errors.New("explaining queries is not supported by driver")
+	errMultipleStatements = errors.New("only a single statement can be explained")
+	errAnalyzeUnsupported = errors.New("analyzing queries is not supported by driver")
+	errBrowseUnsupported  = errors.New("browsing schemas is not supported by driver")
+	errDDLUnsupported     = errors.New("showing DDL is not supported by driver")
+	errNoSnippets         = errors.New("snippet library is not available")
 )
 
-func (m *model) openDatabase(driver string, params connectParams) (string, error) {
+// openDatabase opens a database. If the database is opened from a
+// connection profile, profile is the name of the profile.
+func (m *model) openDatabase(driver string, params connectParams, profile string) (string, error) {
+	params, err := resolveSecrets(m.credStore, driver, params)
+	if err != nil {
+		return "", fmt.Errorf("resolving secrets failed: %w", err)
+	}
+
 	dsn, err := m.getDSN(driver, params)
 	if err != nil {
 		return "", err
@@ -60,6 +172,10 @@ func (m *model) openDatabase(driver string, params connectParams) (string, error
 
 	m.dbInfo[dbID] = info
 
+	if profile != "" {
+		m.profileNames[dbID] = profile
+	}
+
 	return dbID, nil
 }
 
@@ -90,6 +206,372 @@ func (m *model) getTables(dbID string) ([]string, error) {
 	return info.GetTables()
 }
 
+// schemaBrowser returns the schema browser of a database.
+func (m *model) schemaBrowser(dbID string) (schemaBrowser, error) {
+	info := m.dbInfo[dbID]
+	if info == nil {
+		return nil, errDatabaseNotOpen
+	}
+
+	browser, ok := info.(schemaBrowser)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", supportedDrivers[info.Driver()].Name, errBrowseUnsupported)
+	}
+
+	return browser, nil
+}
+
+func (m *model) getSchemas(dbID string) ([]string, error) {
+	browser, err := m.schemaBrowser(dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	return browser.GetSchemas()
+}
+
+func (m *model) getSchemaObjectKinds(dbID string) ([]objectKind, error) {
+	browser, err := m.schemaBrowser(dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	return browser.SchemaObjectKinds(), nil
+}
+
+func (m *model) getSchemaObjects(dbID, schema string, kind objectKind) ([]schemaObject, error) {
+	browser, err := m.schemaBrowser(dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	return browser.GetSchemaObjects(schema, kind)
+}
+
+func (m *model) getSchemaColumns(dbID, schema, table string) ([]column, error) {
+	browser, err := m.schemaBrowser(dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	return browser.GetColumns(schema, table)
+}
+
+func (m *model) getTableObjects(dbID, schema, table string, kind objectKind) ([]schemaObject, error) {
+	browser, err := m.schemaBrowser(dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	return browser.GetTableObjects(schema, table, kind)
+}
+
+// getDDL returns the DDL of a table, view or index. schema is empty for
+// drivers that don't support browsing schemas.
+func (m *model) getDDL(dbID, schema, name string, kind objectKind) (string, error) {
+	info := m.dbInfo[dbID]
+	if info == nil {
+		return "", errDatabaseNotOpen
+	}
+
+	provider, ok := info.(ddlProvider)
+	if !ok {
+		return "", fmt.Errorf("%s: %w", supportedDrivers[info.Driver()].Name, errDDLUnsupported)
+	}
+
+	return provider.GetDDL(schema, name, kind)
+}
+
+// getEditInfo resolves the name of a table whose rows are to be edited
+// against the tables of the database, and returns it together with the
+// table's columns and primary key.
+func (m *model) getEditInfo(dbID, name string) (string, []column, []string, error) {
+	info := m.dbInfo[dbID]
+	if info == nil {
+		return "", nil, nil, errDatabaseNotOpen
+	}
+
+	provider, ok := info.(primaryKeyProvider)
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%s: %w", supportedDrivers[info.Driver()].Name, errNotEditable)
+	}
+
+	tables, err := m.schema.getTables(dbID, info.GetTables)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	table, ok := findTable(tables, name)
+	if !ok {
+		return "", nil, nil, fmt.Errorf("table %s not found: %w", name, errNotEditable)
+	}
+
+	// tables in the search path are listed without their schema.
+	if schema, _, qualified := strings.Cut(name, "."); qualified && !strings.Contains(table, ".") {
+		table = schema + "." + table
+	}
+
+	columns, err := info.GetTableColumns(table)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	primaryKey, err := provider.GetPrimaryKey(table)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return table, columns, primaryKey, nil
+}
+
+// applyChanges runs the statements of an edit in the open transaction of
+// the database, or in a new transaction that is only committed if all
+// statements succeed. In an open transaction, the statements run within a
+// savepoint, so that a failing statement undoes the ones before it.
+func (m *model) applyChanges(ctx context.Context, dbID string, stmts []editStatement) (err error) {
+	info := m.dbInfo[dbID]
+	if info == nil {
+		return errDatabaseNotOpen
+	}
+
+	m.txMtx.Lock()
+	tx := m.tx[dbID]
+	m.txMtx.Unlock()
+
+	if tx != nil {
+		return savepoint(ctx, tx, true, func() error {
+			return runEditStatements(ctx, tx, stmts)
+		})
+	}
+
+	tx, err = info.Conn().BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction failed: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			// a cancelled ctx has already rolled back the transaction.
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				log.Printf("Rolling back changes failed: %v", rbErr)
+			}
+
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("committing changes failed: %w", err)
+		}
+	}()
+
+	return runEditStatements(ctx, tx, stmts)
+}
+
+func runEditStatements(ctx context.Context, tx *sqlx.Tx, stmts []editStatement) error {
+	for idx, stmt := range stmts {
+		res, err := tx.ExecContext(ctx, tx.Rebind(stmt.Query), stmt.Args...)
+		if err != nil {
+			return fmt.Errorf("statement %d failed: %w", idx+1, err)
+		}
+
+		if !stmt.SingleRow {
+			continue
+		}
+
+		if n, err := res.RowsAffected(); err == nil && n != 1 {
+			return fmt.Errorf("statement %d: %w", idx+1, errRowNotChanged)
+		}
+	}
+
+	return nil
+}
+
+// getCompletions returns the completions for the word that ends at the byte
+// offset pos in query, and the offset where that word starts. Besides SQL
+// keywords and the database's tables, the columns of the tables that the
+// statement at pos refers to are offered. A qualified word like "t.na" is
+// only completed with columns of the table or alias t.
+func (m *model) getCompletions(dbID, query string, pos int) (start int, completions []completion, err error) {
+	start, qualifier := completionPrefix(query, pos)
+	prefix := query[start:pos]
+
+	info := m.dbInfo[dbID]
+	if info == nil {
+		if qualifier != "" {
+			return start, nil, nil
+		}
+
+		return start, filterCompletions(keywordCompletions(prefix), prefix), nil
+	}
+
+	tables, err := m.schema.getTables(dbID, info.GetTables)
+	if err != nil {
+		return start, nil, err
+	}
+
+	var refs []tableRef
+
+	if stmt, ok := statementAt(query, pos, driverDialect(info.Driver())); ok {
+		refs = referencedTables(stmt, driverDialect(info.Driver()))
+	}
+
+	columnsOf := func(name string) ([]completion, error) {
+		table, ok := findTable(tables, name)
+		if !ok {
+			return nil, nil
+		}
+
+		columns, err := m.schema.getColumns(dbID, table, func() ([]column, error) {
+			return info.GetTableColumns(table)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		completions := make([]completion, 0, len(columns))
+		for _, col := range columns {
+			completions = append(completions, completion{Text: col.Name, Kind: completionColumn, Detail: col.Type})
+		}
+
+		return completions, nil
+	}
+
+	if qualifier != "" {
+		name := qualifier
+
+		for _, ref := range refs {
+			if strings.EqualFold(ref.Alias, qualifier) {
+				name = ref.Name
+			}
+		}
+
+		completions, err := columnsOf(name)
+		if err != nil {
+			return start, nil, err
+		}
+
+		return start, filterCompletions(completions, prefix), nil
+	}
+
+	seen := make(map[string]bool)
+
+	for _, ref := range refs {
+		columns, err := columnsOf(ref.Name)
+		if err != nil {
+			return start, nil, err
+		}
+
+		for _, col := range columns {
+			if !seen[col.Text] {
+				seen[col.Text] = true
+
+				completions = append(completions, col)
+			}
+		}
+	}
+
+	for _, table := range tables {
+		completions = append(completions, completion{Text: table, Kind: completionTable, Detail: "table"})
+	}
+
+	completions = append(completions, keywordCompletions(prefix)...)
+
+	return start, filterCompletions(completions, prefix), nil
+}
+
+// explainQuery returns the execution plan of query, which must consist of a
+// single statement. If analyze is set, the query is run to get the actual
+// times and row counts, and its changes are rolled back afterwards. If a
+// transaction is open, the query is explained within it.
+func (m *model) explainQuery(ctx context.Context, dbID, query string, analyze bool) (*planNode, error) {
+	info := m.dbInfo[dbID]
+	if info == nil {
+		return nil, errDatabaseNotOpen
+	}
+
+	explainer, ok := info.(planExplainer)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", supportedDrivers[info.Driver()].Name, errExplainUnsupported)
+	}
+
+	explain := explainer.ExplainQuery
+
+	if analyze {
+		analyzer, ok := info.(planAnalyzer)
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", supportedDrivers[info.Driver()].Name, errAnalyzeUnsupported)
+		}
+
+		explain = analyzer.ExplainAnalyzeQuery
+	}
+
+	stmts := splitStatements(query, driverDialect(info.Driver()))
+
+	switch len(stmts) {
+	case 0:
+		return nil, errEmptyQuery
+	case 1:
+	default:
+		return nil, errMultipleStatements
+	}
+
+	conn, inTx := m.conn(dbID, info)
+
+	switch {
+	case inTx:
+		// a failing statement would abort the transaction on PostgreSQL.
+		var plan *planNode
+
+		err := savepoint(ctx, conn.(*sqlx.Tx), false, func() (err error) {
+			plan, err = explain(ctx, conn, stmts[0].Text)
+
+			return err
+		})
+
+		return plan, err
+	case analyze:
+		tx, err := info.Conn().BeginTxx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("starting transaction failed: %w", err)
+		}
+
+		defer func() {
+			if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+				log.Printf("Rolling back explained query failed: %v", err)
+			}
+		}()
+
+		return explain(ctx, tx, stmts[0].Text)
+	default:
+		return explain(ctx, conn, stmts[0].Text)
+	}
+}
+
+// canAnalyzeQueries returns whether the driver of a database can explain
+// queries with their actual times and row counts.
+func (m *model) canAnalyzeQueries(dbID string) bool {
+	_, ok := m.dbInfo[dbID].(planAnalyzer)
+
+	return ok
+}
+
+// refreshSchema discards the cached tables and columns of a database, so
+// that they are loaded again for the next completion.
+func (m *model) refreshSchema(dbID string) {
+	m.schema.invalidate(dbID)
+}
+
+// getDriver returns the driver of an open database, or an empty string if
+// the database isn't open.
+func (m *model) getDriver(dbID string) string {
+	info := m.dbInfo[dbID]
+	if info == nil {
+		return ""
+	}
+
+	return info.Driver()
+}
+
 func (m *model) getTableColumns(dbID, tbl string) ([]column, error) {
 	info := m.dbInfo[dbID]
 	if info == nil {
@@ -99,56 +581,529 @@ func (m *model) getTableColumns(dbID, tbl string) ([]column, error) {
 	return info.GetTableColumns(tbl)
 }
 
-func (m *model) execQuery(dbID, query string) error {
+// execQuery executes the statements of query. If params is set, the
+// placeholders in the statements are bound to its values, which are
+// indexed by the placeholder names returned by queryParamNames.
+func (m *model) execQuery(ctx context.Context, dbID, query string, params map[string]interface{}) error {
 	info := m.dbInfo[dbID]
 	if info == nil {
 		return errDatabaseNotOpen
 	}
 
-	rows, err := info.Conn().Queryx(query)
-	if err != nil {
-		return fmt.Errorf("query failed: %w", err)
+	stmts := splitStatements(query, driverDialect(info.Driver()))
+	if len(stmts) == 0 {
+		return errEmptyQuery
 	}
-	defer rows.Close()
 
-	m.ctrl.clearResultTable()
+	m.ctrl.clearQueryResults()
 
-	columns, err := rows.Columns()
+	startTime := time.Now()
+	historyID := m.recordQueryStart(startTime, dbID, query)
+
+	err := m.execStatements(ctx, info, dbID, stmts, params, historyID)
+
+	m.recordQueryFinish(historyID, time.Since(startTime), err)
+
+	return err
+}
+
+func (m *model) execStatements(ctx context.Context, info dbInfo, dbID string, stmts []sqlStatement, params map[string]interface{}, historyID int64) error {
+	for idx, stmt := range stmts {
+		result, err := m.execStatement(ctx, info, dbID, stmt, params)
+		if err != nil {
+			if len(stmts) > 1 {
+				return fmt.Errorf("statement %d: %w", idx+1, err)
+			}
+
+			return err
+		}
+
+		m.recordQueryRows(historyID, result.rowsAffected)
+
+		result.onDone = func(rowCount int64) {
+			m.recordQueryRows(historyID, rowCount)
+		}
+
+		// an open cursor holds on to its connection, which keeps later
+		// statements waiting for locks on SQLite and ties up a connection
+		// per result otherwise, so only the last result is fetched on demand.
+		if idx < len(stmts)-1 {
+			if err := result.readAll(); err != nil {
+				return fmt.Errorf("statement %d: %w", idx+1, err)
+			}
+		}
+
+		if err := m.ctrl.addQueryResult(result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *model) setHistory(history *queryHistory) {
+	m.history = history
+}
+
+// recordQueryStart adds a query execution to the history and returns the ID
+// of the history entry, or 0 if no history is kept.
+func (m *model) recordQueryStart(startTime time.Time, dbID, query string) int64 {
+	if m.history == nil {
+		return 0
+	}
+
+	id, err := m.history.add(startTime, m.getDatabaseName(dbID), m.getHistoryKey(dbID), query)
 	if err != nil {
-		return fmt.Errorf("listing columns failed: %w", err)
+		log.Printf("Recording query in history failed: %v", err)
+	}
+
+	return id
+}
+
+func (m *model) recordQueryFinish(historyID int64, duration time.Duration, execErr error) {
+	if historyID == 0 {
+		return
+	}
+
+	if err := m.history.finish(historyID, duration, execErr); err != nil {
+		log.Printf("Recording query result in history failed: %v", err)
+	}
+}
+
+func (m *model) recordQueryRows(historyID int64, rowCount int64) {
+	if historyID == 0 || rowCount == 0 {
+		return
+	}
+
+	if err := m.history.addRows(historyID, rowCount); err != nil {
+		log.Printf("Recording row count in history failed: %v", err)
+	}
+}
+
+// getHistory returns the last limit history entries of a database, or of
+// all databases if dbID is empty.
+func (m *model) getHistory(limit int, dbID string) ([]historyEntry, error) {
+	if m.history == nil {
+		return nil, errNoHistory
+	}
+
+	key := ""
+	if dbID != "" {
+		key = m.getHistoryKey(dbID)
+	}
+
+	return m.history.recent(limit, key)
+}
+
+// getHistoryKey returns what identifies a database in the history: the
+// profile it was opened from, or else its connection, like the database
+// of a query tab in the session.
+func (m *model) getHistoryKey(dbID string) string {
+	if profile := m.profileNames[dbID]; profile != "" {
+		return "profile:" + profile
 	}
 
-	m.ctrl.setResultTableColumns(columns)
+	return m.getConnectionKey(dbID)
+}
+
+func (m *model) setSnippetLibrary(snippets *snippetLibrary) {
+	m.snippets = snippets
+}
+
+func (m *model) getSnippets() ([]snippet, error) {
+	if m.snippets == nil {
+		return nil, errNoSnippets
+	}
+
+	return m.snippets.list()
+}
 
-	for rows.Next() {
-		row, err := rows.SliceScan()
+func (m *model) saveSnippet(s snippet) (snippet, error) {
+	if m.snippets == nil {
+		return s, errNoSnippets
+	}
+
+	return m.snippets.save(s)
+}
+
+func (m *model) execStatement(ctx context.Context, info dbInfo, dbID string, stmt sqlStatement, params map[string]interface{}) (*queryResult, error) {
+	switch stmt.keyword() {
+	case "BEGIN", "START":
+		if err := m.beginTx(dbID); err != nil {
+			return nil, err
+		}
+
+		return &queryResult{dbID: dbID, message: "Transaction started"}, nil
+	case "COMMIT", "END":
+		if err := m.commitTx(dbID); err != nil {
+			return nil, err
+		}
+
+		return &queryResult{dbID: dbID, message: "Transaction committed"}, nil
+	case "ROLLBACK":
+		if !stmt.hasKeyword("TO") { // ROLLBACK TO SAVEPOINT is passed on to the database.
+			if err := m.rollbackTx(dbID); err != nil {
+				return nil, err
+			}
+
+			return &queryResult{dbID: dbID, message: "Transaction rolled back"}, nil
+		}
+	}
+
+	conn, inTx := m.conn(dbID, info)
+
+	query, args := stmt.Text, []interface{}(nil)
+
+	if params != nil {
+		var err error
+
+		query, args, err = bindParams(stmt, params, driverDialect(info.Driver()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !stmt.returnsRows() && !supportedDrivers[info.Driver()].QueryOnly {
+		res, err := conn.ExecContext(ctx, query, args...)
 		if err != nil {
-			return fmt.Errorf("scanning row failed: %w", err)
+			return nil, fmt.Errorf("statement failed: %w", err)
 		}
 
-		var values []string
+		result := &queryResult{dbID: dbID, message: "Statement executed successfully"}
 
-		for _, v := range row {
-			values = append(values, fmt.Sprint(v))
+		if stmt.isDML() {
+			if n, err := res.RowsAffected(); err == nil {
+				result.message = fmt.Sprintf("%d rows affected", n)
+				result.rowsAffected = n
+			}
 		}
 
-		m.ctrl.addResultTableRow(values)
+		return result, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	rows, err := conn.QueryxContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		cancel()
+
+		return nil, fmt.Errorf("listing columns failed: %w", err)
+	}
+
+	if len(columns) == 0 {
+		rows.Close()
+		cancel()
+
+		return &queryResult{dbID: dbID, message: "Statement executed successfully"}, nil
+	}
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		cancel()
+
+		return nil, fmt.Errorf("listing column types failed: %w", err)
+	}
+
+	types := make([]string, 0, len(columnTypes))
+	for _, ct := range columnTypes {
+		types = append(types, ct.DatabaseTypeName())
+	}
+
+	result := &queryResult{
+		dbID:     dbID,
+		columns:  columns,
+		types:    types,
+		table:    sourceTable(stmt, driverDialect(info.Driver())),
+		selected: selectedColumns(stmt, driverDialect(info.Driver())),
+		rows:     rows,
+		cancel:   cancel,
+	}
+
+	// a transaction is bound to a single connection, which can't be used
+	// for the next statement while the cursor is still open.
+	if inTx {
+		if err := result.readAll(); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// conn returns the open transaction of a database if there is one, and the
+// database's connection pool otherwise.
+func (m *model) conn(dbID string, info dbInfo) (conn sqlx.ExtContext, inTx bool) {
+	m.txMtx.Lock()
+	defer m.txMtx.Unlock()
+
+	if tx := m.tx[dbID]; tx != nil {
+		return tx, true
+	}
+
+	return info.Conn(), false
+}
+
+func (m *model) beginTx(dbID string) error {
+	info := m.dbInfo[dbID]
+	if info == nil {
+		return errDatabaseNotOpen
+	}
+
+	m.txMtx.Lock()
+	defer m.txMtx.Unlock()
+
+	if m.tx[dbID] != nil {
+		return errTxAlreadyOpen
+	}
+
+	tx, err := info.Conn().Beginx()
+	if err != nil {
+		return fmt.Errorf("starting transaction failed: %w", err)
+	}
+
+	m.tx[dbID] = tx
+
+	return nil
+}
+
+func (m *model) commitTx(dbID string) error {
+	tx, err := m.takeTx(dbID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+func (m *model) rollbackTx(dbID string) error {
+	tx, err := m.takeTx(dbID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Rollback(); err != nil {
+		return fmt.Errorf("rolling back transaction failed: %w", err)
 	}
 
 	return nil
 }
 
+const savepointName = "koios_savepoint"
+
+// savepoint runs fn within a savepoint of tx. The changes of fn are rolled
+// back if it fails or if keep is false, without affecting the rest of the
+// transaction.
+func savepoint(ctx context.Context, tx *sqlx.Tx, keep bool, fn func() error) (err error) {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepointName); err != nil {
+		return fmt.Errorf("creating savepoint failed: %w", err)
+	}
+
+	defer func() {
+		if err == nil && keep {
+			if _, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepointName); err != nil {
+				err = fmt.Errorf("releasing savepoint failed: %w", err)
+			}
+
+			return
+		}
+
+		// ctx may have been cancelled, which must not keep the changes.
+		for _, stmt := range []string{"ROLLBACK TO SAVEPOINT ", "RELEASE SAVEPOINT "} {
+			if _, rbErr := tx.ExecContext(context.Background(), stmt+savepointName); rbErr != nil {
+				log.Printf("%s%s failed: %v", stmt, savepointName, rbErr)
+			}
+		}
+	}()
+
+	return fn()
+}
+
+// takeTx removes the open transaction of a database from the model and
+// returns it.
+func (m *model) takeTx(dbID string) (*sqlx.Tx, error) {
+	m.txMtx.Lock()
+	defer m.txMtx.Unlock()
+
+	tx := m.tx[dbID]
+	if tx == nil {
+		return nil, errNoTxOpen
+	}
+
+	delete(m.tx, dbID)
+
+	return tx, nil
+}
+
+func (m *model) inTransaction(dbID string) bool {
+	m.txMtx.Lock()
+	defer m.txMtx.Unlock()
+
+	return m.tx[dbID] != nil
+}
+
+// openTransactions returns the dbIDs of all databases with an open
+// transaction.
+func (m *model) openTransactions() []string {
+	m.txMtx.Lock()
+	defer m.txMtx.Unlock()
+
+	dbIDs := make([]string, 0, len(m.tx))
+	for dbID := range m.tx {
+		dbIDs = append(dbIDs, dbID)
+	}
+
+	sort.Strings(dbIDs)
+
+	return dbIDs
+}
+
+// queryResult is the result of an executed statement. For statements that
+// return rows, it holds the open cursor from which rows are fetched on
+// demand, so that large results don't need to be held in memory in their
+// entirety. For all other statements, it only holds a message describing
+// the outcome.
+type queryResult struct {
+	dbID     string
+	columns  []string
+	types    []string // database type names of the columns
+	table    string   // table that the rows belong to, empty if the rows can't be edited
+	selected []string // items of the select list as returned by selectedColumns
+	rows     *sqlx.Rows
+	cancel   context.CancelFunc
+	message  string
+	buffer   [][]interface{} // rows that have already been read from the cursor
+
+	rowsAffected int64
+	fetched      int64            // number of rows read from the cursor
+	onDone       func(rows int64) // called when all rows have been fetched
+}
+
+// fetchRows fetches up to n rows from the result. Values are normalized
+// using normalizeValue. done is set to true when the result has been
+// exhausted, in which case the result is closed.
+func (r *queryResult) fetchRows(n int) (values [][]interface{}, done bool, err error) {
+	if r.rows == nil {
+		return nil, true, nil
+	}
+
+	if len(r.buffer) > 0 {
+		if n > len(r.buffer) {
+			n = len(r.buffer)
+		}
+
+		values, r.buffer = r.buffer[:n], r.buffer[n:]
+
+		return values, false, nil
+	}
+
+	for len(values) < n {
+		if !r.rows.Next() {
+			err := r.rows.Err()
+
+			r.close()
+
+			if err != nil {
+				return values, true, fmt.Errorf("iterating over result failed: %w", err)
+			}
+
+			if r.onDone != nil {
+				r.onDone(r.fetched)
+				r.onDone = nil
+			}
+
+			return values, true, nil
+		}
+
+		row, err := r.rows.SliceScan()
+		if err != nil {
+			r.close()
+
+			return values, true, fmt.Errorf("scanning row failed: %w", err)
+		}
+
+		for idx, v := range row {
+			row[idx] = normalizeValue(v, r.types[idx])
+		}
+
+		values = append(values, row)
+		r.fetched++
+	}
+
+	return values, false, nil
+}
+
+// readAll reads all rows from the cursor into the buffer and closes the
+// cursor.
+func (r *queryResult) readAll() error {
+	values, _, err := r.fetchRows(math.MaxInt)
+	r.buffer = values
+
+	return err
+}
+
+func (r *queryResult) close() {
+	if r.rows == nil {
+		return
+	}
+
+	if err := r.rows.Close(); err != nil {
+		log.Printf("Closing result of database %s failed: %v", r.dbID, err)
+	}
+
+	r.cancel()
+}
+
 func (m *model) getDatabaseName(dbID string) string {
+	if profile := m.profileNames[dbID]; profile != "" {
+		return profile
+	}
+
 	return m.dbInfo[dbID].Name()
 }
 
+// getProfileName returns the name of the profile that a database was
+// opened from, or an empty string if it wasn't opened from a profile.
+func (m *model) getProfileName(dbID string) string {
+	return m.profileNames[dbID]
+}
+
+// getConnectionKey returns the driver and the connect parameters that
+// aren't secret of a database, which identify its connection.
+func (m *model) getConnectionKey(dbID string) string {
+	info := m.dbInfo[dbID]
+	if info == nil {
+		return ""
+	}
+
+	return credentialKey(info.Driver(), info.ConnectParams())
+}
+
 func (m *model) getSession() []sessionDataDB {
 	dbs := make([]sessionDataDB, 0, len(m.dbInfo))
 
-	for _, dbInfo := range m.dbInfo {
+	for dbID, dbInfo := range m.dbInfo {
+		params, err := stripSecrets(m.credStore, dbInfo.Driver(), dbInfo.ConnectParams())
+		if err != nil {
+			log.Printf("Storing secrets of database %s failed: %v", dbID, err)
+		}
+
 		dbs = append(dbs, sessionDataDB{
+			Profile:       m.profileNames[dbID],
 			Driver:        dbInfo.Driver(),
-			ConnectParams: dbInfo.ConnectParams(),
+			ConnectParams: params,
 		})
 	}
 
@@ -161,11 +1116,19 @@ func (m *model) closeDatabase(dbID string) string {
 		log.Printf("Couldn't close database %s because it doesn't exist", dbID)
 	}
 
+	if m.inTransaction(dbID) {
+		if err := m.rollbackTx(dbID); err != nil {
+			log.Printf("Rolling back open transaction of database %s failed: %v", dbID, err)
+		}
+	}
+
 	if err := db.Conn().Close(); err != nil {
 		log.Printf("Closing connection to database %s failed: %v", dbID, err)
 	}
 
 	delete(m.dbInfo, dbID)
+	delete(m.profileNames, dbID)
+	m.schema.invalidate(dbID)
 
 	for newDbID := range m.dbInfo {
 		return newDbID
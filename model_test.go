@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// openTestDatabase opens a new SQLite database with the table t.
+func openTestDatabase(t *testing.T) (*model, string) {
+	t.Helper()
+
+	m := newModel()
+
+	dbID, err := m.openDatabase("sqlite", connectParams{"file": filepath.Join(t.TempDir(), "test.db")}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		m.dbInfo[dbID].Conn().Close()
+	})
+
+	if _, err := m.dbInfo[dbID].Conn().Exec("CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO t VALUES (1, 'a'), (2, 'b')"); err != nil {
+		t.Fatal(err)
+	}
+
+	return m, dbID
+}
+
+func testTableNames(t *testing.T, m *model, dbID string) []string {
+	t.Helper()
+
+	var names []string
+
+	if err := m.dbInfo[dbID].Conn().Select(&names, "SELECT name FROM t ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+
+	return names
+}
+
+func TestGetEditInfo(t *testing.T) {
+	m, dbID := openTestDatabase(t)
+
+	table, columns, primaryKey, err := m.getEditInfo(dbID, "T")
+	if err != nil {
+		t.Fatalf("getEditInfo returned error: %v", err)
+	}
+
+	if table != "t" || len(columns) != 2 || !reflect.DeepEqual(primaryKey, []string{"id"}) {
+		t.Errorf("getEditInfo = %q, %v, %v, want t with 2 columns and primary key id", table, columns, primaryKey)
+	}
+
+	if _, _, _, err := m.getEditInfo(dbID, "missing"); err == nil {
+		t.Errorf("getEditInfo of missing table returned no error")
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	update := []editStatement{{Query: "UPDATE t SET name = ? WHERE id = ?", Args: []interface{}{"x", int64(1)}, SingleRow: true}}
+	failing := append(update, editStatement{Query: "DELETE FROM t WHERE id = ?", Args: []interface{}{int64(3)}, SingleRow: true})
+
+	tests := []struct {
+		name    string
+		inTx    bool
+		cancel  bool
+		stmts   []editStatement
+		wantErr bool
+		want    []string
+	}{
+		{name: "applied", stmts: update, want: []string{"x", "b"}},
+		{name: "failing statement", stmts: failing, wantErr: true, want: []string{"a", "b"}},
+		{name: "cancelled", cancel: true, stmts: update, wantErr: true, want: []string{"a", "b"}},
+		{name: "applied in transaction", inTx: true, stmts: update, want: []string{"x", "b"}},
+		{name: "failing statement in transaction", inTx: true, stmts: failing, wantErr: true, want: []string{"a", "b"}},
+		{name: "cancelled in transaction", inTx: true, cancel: true, stmts: update, wantErr: true, want: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, dbID := openTestDatabase(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if tt.cancel {
+				cancel()
+			}
+
+			if tt.inTx {
+				if err := m.beginTx(dbID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := m.applyChanges(ctx, dbID, tt.stmts); (err != nil) != tt.wantErr {
+				t.Errorf("applyChanges returned error %v, want error: %t", err, tt.wantErr)
+			}
+
+			if tt.inTx {
+				if err := m.commitTx(dbID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := testTableNames(t, m, dbID); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("table contains %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	m := newModel()
+
+	history, err := openQueryHistory(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.close()
+
+	m.setHistory(history)
+
+	// both databases have the same name.
+	var dbIDs []string
+
+	for _, query := range []string{"SELECT 1", "SELECT 2"} {
+		dbID, err := m.openDatabase("sqlite", connectParams{"file": filepath.Join(t.TempDir(), "test.db")}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.dbInfo[dbID].Conn().Close()
+
+		m.recordQueryStart(time.Now(), dbID, query)
+
+		dbIDs = append(dbIDs, dbID)
+	}
+
+	entries, err := m.getHistory(10, dbIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].Query != "SELECT 1" || entries[0].Database != "test.db" {
+		t.Errorf("getHistory = %+v, want only SELECT 1 on test.db", entries)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var errMissingParam = errors.New("no value for parameter")
+
+// queryParam is a placeholder for a parameter in a statement: a name like
+// :user_id, a position like $1, or ?, which is numbered by its occurrence
+// in the statement, e.g. ?2 for the second ?.
+type queryParam struct {
+	Name  string
+	Start int // offset of the placeholder in the statement's text
+	End   int
+}
+
+// statementParams returns the placeholders of a statement in the order in
+// which they appear. Placeholders in strings and comments are ignored, as
+// are PostgreSQL type casts like ::int. In dialects with dollar parameters,
+// ? is only a placeholder where an operand is expected, as it is also an
+// operator of JSONB, like ?| and ?&.
+func statementParams(stmt sqlStatement, dialect sqlDialect) []queryParam {
+	var (
+		params    []queryParam
+		questions int
+	)
+
+	tokens := stmt.Tokens
+
+	// token offsets are relative to the whole query, while leading
+	// whitespace is trimmed from the statement's text.
+	textStart := stmt.Start
+	for _, tok := range tokens {
+		if tok.Kind != tokenWhitespace {
+			textStart = tok.Start
+
+			break
+		}
+	}
+
+	for idx, tok := range tokens {
+		if tok.Kind != tokenPunct {
+			continue
+		}
+
+		var next *sqlToken
+		if idx+1 < len(tokens) && tokens[idx+1].Start == tok.End {
+			next = &tokens[idx+1]
+		}
+
+		switch {
+		case tok.Text == "?" && dialect.DollarParams && isQuestionOperator(tokens[:idx], next):
+			continue
+		case tok.Text == "?":
+			questions++
+			params = append(params, queryParam{Name: "?" + strconv.Itoa(questions), Start: tok.Start - textStart, End: tok.End - textStart})
+		case tok.Text == ":" && next != nil && next.Kind == tokenWord && (idx == 0 || tokens[idx-1].Text != ":"):
+			params = append(params, queryParam{Name: ":" + next.Text, Start: tok.Start - textStart, End: next.End - textStart})
+		case tok.Text == "$" && next != nil && next.Kind == tokenNumber && isPosition(next.Text):
+			params = append(params, queryParam{Name: "$" + next.Text, Start: tok.Start - textStart, End: next.End - textStart})
+		}
+	}
+
+	return params
+}
+
+// isQuestionOperator returns whether a ? that follows the tokens before it
+// and is directly followed by next is an operator rather than a
+// placeholder: it is part of ?| or ?&, or it follows an operand.
+func isQuestionOperator(before []sqlToken, next *sqlToken) bool {
+	if next != nil && (next.Text == "|" || next.Text == "&") {
+		return true
+	}
+
+	for idx := len(before) - 1; idx >= 0; idx-- {
+		tok := before[idx]
+
+		switch tok.Kind {
+		case tokenWhitespace, tokenComment:
+			continue
+		case tokenWord:
+			return !reservedWords[strings.ToUpper(tok.Text)]
+		case tokenQuotedIdent, tokenString, tokenNumber:
+			return true
+		default:
+			return tok.Text == ")" || tok.Text == "]"
+		}
+	}
+
+	return false
+}
+
+func isPosition(s string) bool {
+	n, err := strconv.Atoi(s)
+
+	return err == nil && n > 0
+}
+
+// queryParamNames returns the names of the parameters of all statements of
+// query, each only once, in the order in which they first appear.
+func queryParamNames(query string, dialect sqlDialect) []string {
+	var (
+		names []string
+		seen  = make(map[string]bool)
+	)
+
+	for _, stmt := range splitStatements(query, dialect) {
+		for _, param := range statementParams(stmt, dialect) {
+			if !seen[param.Name] {
+				seen[param.Name] = true
+				names = append(names, param.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+// bindParams replaces the placeholders of a statement with the placeholders
+// of the dialect, ? or $1, $2 and so on, and returns the values for them in
+// order. A statement without placeholders is returned unchanged.
+func bindParams(stmt sqlStatement, values map[string]interface{}, dialect sqlDialect) (string, []interface{}, error) {
+	params := statementParams(stmt, dialect)
+	if len(params) == 0 {
+		return stmt.Text, nil, nil
+	}
+
+	var (
+		sb   strings.Builder
+		args = make([]interface{}, 0, len(params))
+		pos  int
+	)
+
+	for _, param := range params {
+		value, ok := values[param.Name]
+		if !ok {
+			return "", nil, fmt.Errorf("%s: %w", param.Name, errMissingParam)
+		}
+
+		args = append(args, value)
+
+		sb.WriteString(stmt.Text[pos:param.Start])
+
+		if dialect.DollarParams {
+			sb.WriteString("$" + strconv.Itoa(len(args)))
+		} else {
+			sb.WriteString("?")
+		}
+
+		pos = param.End
+	}
+
+	sb.WriteString(stmt.Text[pos:])
+
+	return sb.String(), args, nil
+}
+
+// paramValue converts the entered value of a parameter to an integer or a
+// floating point number if it is written like one, for when the user wants
+// to use it where the database requires a number, e.g. in LIMIT. Everything
+// else stays text.
+func paramValue(text string) interface{} {
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil && strconv.FormatInt(i, 10) == text {
+		return i
+	}
+
+	if f, err := strconv.ParseFloat(text, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == text {
+		return f
+	}
+
+	return text
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQueryParamNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect sqlDialect
+		query   string
+		want    []string
+	}{
+		{
+			name:    "no parameters",
+			dialect: sqliteDialect,
+			query:   "SELECT * FROM t",
+		},
+		{
+			name:    "question marks are numbered",
+			dialect: sqliteDialect,
+			query:   "SELECT * FROM t WHERE a = ? AND b = ?",
+			want:    []string{"?1", "?2"},
+		},
+		{
+			name:    "named parameters are listed once",
+			dialect: sqliteDialect,
+			query:   "SELECT * FROM t WHERE a = :id OR b = :id; DELETE FROM u WHERE c = :name",
+			want:    []string{":id", ":name"},
+		},
+		{
+			name:    "positional parameters",
+			dialect: postgresDialect,
+			query:   "SELECT * FROM t WHERE a = $2 AND b = $1",
+			want:    []string{"$2", "$1"},
+		},
+		{
+			name:    "strings, comments and casts",
+			dialect: postgresDialect,
+			query:   "SELECT 'why?', ':x', a::int -- :y ?\nFROM t WHERE id = :id",
+			want:    []string{":id"},
+		},
+		{
+			name:    "jsonb operators",
+			dialect: postgresDialect,
+			query:   "SELECT * FROM t WHERE doc ? 'a' AND doc ?| array['b'] AND doc ?& array['c'] AND (doc->'d') ? 'e' AND id = ?",
+			want:    []string{"?1"},
+		},
+		{
+			name:    "question mark after keyword",
+			dialect: postgresDialect,
+			query:   "SELECT * FROM t WHERE id IN (?, ?) LIMIT ?",
+			want:    []string{"?1", "?2", "?3"},
+		},
+		{
+			name:    "question mark is no operator in mysql",
+			dialect: mysqlDialect,
+			query:   "SELECT * FROM t WHERE flags = ?|4",
+			want:    []string{"?1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := queryParamNames(tt.query, tt.dialect)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queryParamNames(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  sqlDialect
+		query    string
+		values   map[string]interface{}
+		want     string
+		wantArgs []interface{}
+	}{
+		{
+			name:    "statement without parameters is unchanged",
+			dialect: postgresDialect,
+			query:   "  SELECT 'why?' FROM t WHERE doc ? 'a'",
+			want:    "SELECT 'why?' FROM t WHERE doc ? 'a'",
+		},
+		{
+			name:     "question marks",
+			dialect:  sqliteDialect,
+			query:    "SELECT * FROM t WHERE a = ? AND b = ?",
+			values:   map[string]interface{}{"?1": "x", "?2": "2"},
+			want:     "SELECT * FROM t WHERE a = ? AND b = ?",
+			wantArgs: []interface{}{"x", "2"},
+		},
+		{
+			name:     "named parameters in sqlite",
+			dialect:  sqliteDialect,
+			query:    "SELECT * FROM t WHERE note = 'why?' AND id = :id OR parent = :id",
+			values:   map[string]interface{}{":id": int64(7)},
+			want:     "SELECT * FROM t WHERE note = 'why?' AND id = ? OR parent = ?",
+			wantArgs: []interface{}{int64(7), int64(7)},
+		},
+		{
+			name:     "named parameters in postgres",
+			dialect:  postgresDialect,
+			query:    "SELECT * FROM t WHERE note = 'why?' AND doc ? 'k' AND id = :id",
+			values:   map[string]interface{}{":id": "1.5"},
+			want:     "SELECT * FROM t WHERE note = 'why?' AND doc ? 'k' AND id = $1",
+			wantArgs: []interface{}{"1.5"},
+		},
+		{
+			name:     "positional parameters are renumbered",
+			dialect:  postgresDialect,
+			query:    "SELECT $2, $1",
+			values:   map[string]interface{}{"$1": "a", "$2": "b"},
+			want:     "SELECT $1, $2",
+			wantArgs: []interface{}{"b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts := splitStatements(tt.query, tt.dialect)
+			if len(stmts) != 1 {
+				t.Fatalf("splitStatements(%q) returned %d statements", tt.query, len(stmts))
+			}
+
+			got, args, err := bindParams(stmts[0], tt.values, tt.dialect)
+			if err != nil {
+				t.Fatalf("bindParams returned error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("bindParams(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("bindParams(%q) args = %#v, want %#v", tt.query, args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBindParamsMissingValue(t *testing.T) {
+	stmts := splitStatements("SELECT :a, :b", sqliteDialect)
+
+	if _, _, err := bindParams(stmts[0], map[string]interface{}{":a": "1"}, sqliteDialect); !errors.Is(err, errMissingParam) {
+		t.Errorf("bindParams returned error %v, want %v", err, errMissingParam)
+	}
+}
+
+func TestParamValue(t *testing.T) {
+	tests := []struct {
+		text string
+		want interface{}
+	}{
+		{text: "-4", want: int64(-4)},
+		{text: "1.5", want: 1.5},
+		{text: "007", want: "007"},
+		{text: "1e3", want: "1e3"},
+		{text: "abc", want: "abc"},
+	}
+
+	for _, tt := range tests {
+		if got := paramValue(tt.text); got != tt.want {
+			t.Errorf("paramValue(%q) = %#v, want %#v", tt.text, got, tt.want)
+		}
+	}
+}
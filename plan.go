@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var errEmptyPlan = errors.New("query plan is empty")
+
+// planExplainer is implemented by the dbInfo of drivers that can show the
+// execution plan of a query. The query is explained using conn, which is
+// the database or its open transaction.
+type planExplainer interface {
+	ExplainQuery(ctx context.Context, conn sqlx.QueryerContext, query string) (*planNode, error)
+}
+
+// planAnalyzer is implemented by the dbInfo of drivers that can run a query
+// to report the actual times and row counts of its plan. Changes that the
+// query makes must be rolled back by the caller.
+type planAnalyzer interface {
+	ExplainAnalyzeQuery(ctx context.Context, conn sqlx.QueryerContext, query string) (*planNode, error)
+}
+
+// planNode is a node of a query plan. Cost, Rows and Time are empty if the
+// driver doesn't report them.
+type planNode struct {
+	Label    string
+	Cost     string
+	Rows     string
+	Time     string
+	Details  []string // conditions, filters and the like
+	Children []*planNode
+}
+
+// summary returns the cost, rows and time of the node.
+func (n *planNode) summary() string {
+	var parts []string
+
+	for _, part := range []struct{ Name, Value string }{
+		{"cost", n.Cost},
+		{"rows", n.Rows},
+		{"time", n.Time},
+	} {
+		if part.Value != "" {
+			parts = append(parts, part.Name+"="+part.Value)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// pgPlanDetails are the keys of a PostgreSQL plan node that are shown as
+// details.
+var pgPlanDetails = []string{
+	"Index Cond", "Recheck Cond", "Hash Cond", "Merge Cond", "Join Filter", "Filter",
+	"Sort Key", "Group Key", "Output", "Rows Removed by Filter",
+}
+
+// parsePgPlan parses the output of PostgreSQL's EXPLAIN (FORMAT JSON).
+func parsePgPlan(data []byte) (*planNode, error) {
+	var plans []struct {
+		Plan          map[string]interface{} `json:"Plan"`
+		PlanningTime  *float64               `json:"Planning Time"`
+		ExecutionTime *float64               `json:"Execution Time"`
+	}
+
+	// numbers are kept as they are, so that large row counts aren't shown in
+	// exponential notation.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(&plans); err != nil {
+		return nil, fmt.Errorf("parsing query plan failed: %w", err)
+	}
+
+	if len(plans) == 0 {
+		return nil, errEmptyPlan
+	}
+
+	root := pgPlanNode(plans[0].Plan)
+
+	if plans[0].PlanningTime != nil {
+		root.Details = append(root.Details, fmt.Sprintf("Planning Time: %.3f ms", *plans[0].PlanningTime))
+	}
+
+	if plans[0].ExecutionTime != nil {
+		root.Details = append(root.Details, fmt.Sprintf("Execution Time: %.3f ms", *plans[0].ExecutionTime))
+	}
+
+	return root, nil
+}
+
+func pgPlanNode(plan map[string]interface{}) *planNode {
+	str := func(key string) string {
+		if v, ok := plan[key]; ok {
+			return fmt.Sprint(v)
+		}
+
+		return ""
+	}
+
+	label := str("Node Type")
+
+	if joinType := str("Join Type"); joinType != "" && joinType != "Inner" {
+		label = joinType + " " + label
+	}
+
+	if indexName := str("Index Name"); indexName != "" {
+		label += " using " + indexName
+	}
+
+	if relation := str("Relation Name"); relation != "" {
+		label += " on " + relation
+
+		if alias := str("Alias"); alias != "" && alias != relation {
+			label += " " + alias
+		}
+	}
+
+	node := &planNode{Label: label}
+
+	if _, ok := plan["Total Cost"]; ok {
+		node.Cost = str("Startup Cost") + ".." + str("Total Cost")
+	}
+
+	node.Rows = str("Plan Rows")
+
+	if _, ok := plan["Actual Total Time"]; ok {
+		node.Rows = str("Actual Rows") + " (estimated " + node.Rows + ")"
+		node.Time = str("Actual Startup Time") + ".." + str("Actual Total Time") + " ms"
+
+		if loops := str("Actual Loops"); loops != "" && loops != "1" {
+			node.Time += " × " + loops
+		}
+	}
+
+	for _, key := range pgPlanDetails {
+		switch v := plan[key].(type) {
+		case nil:
+		case []interface{}:
+			parts := make([]string, 0, len(v))
+			for _, part := range v {
+				parts = append(parts, fmt.Sprint(part))
+			}
+
+			node.Details = append(node.Details, key+": "+strings.Join(parts, ", "))
+		default:
+			node.Details = append(node.Details, key+": "+fmt.Sprint(v))
+		}
+	}
+
+	if children, ok := plan["Plans"].([]interface{}); ok {
+		for _, child := range children {
+			if childPlan, ok := child.(map[string]interface{}); ok {
+				node.Children = append(node.Children, pgPlanNode(childPlan))
+			}
+		}
+	}
+
+	return node
+}
+
+// parseSQLitePlan builds a plan from the rows of SQLite's EXPLAIN QUERY
+// PLAN, which refer to their parent row by ID.
+func parseSQLitePlan(rows *sql.Rows) (*planNode, error) {
+	root := &planNode{Label: "QUERY PLAN"}
+	nodes := map[int]*planNode{0: root}
+
+	for rows.Next() {
+		var (
+			id, parent, notUsed int
+			detail              string
+		)
+
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		node := &planNode{Label: detail}
+		nodes[id] = node
+
+		parentNode, ok := nodes[parent]
+		if !ok {
+			parentNode = root
+		}
+
+		parentNode.Children = append(parentNode.Children, node)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over query plan failed: %w", err)
+	}
+
+	return root, nil
+}
+
+// parseIndentedPlan builds a plan from a textual plan like Athena's, in
+// which the nesting of nodes is expressed by indentation. Lines that start
+// with "- " and unindented lines like "Fragment 1 [SOURCE]" are nodes, all
+// other lines are details of the preceding node. Estimates of a node are
+// shown as its cost.
+func parseIndentedPlan(lines []string) (*planNode, error) {
+	type level struct {
+		indent int
+		node   *planNode
+	}
+
+	root := &planNode{Label: "Query Plan"}
+	stack := []level{{indent: -1, node: root}}
+
+	for _, line := range lines {
+		for _, l := range strings.Split(line, "\n") {
+			text := strings.TrimSpace(l)
+			if text == "" {
+				continue
+			}
+
+			indent := len(l) - len(strings.TrimLeft(l, " "))
+
+			if !strings.HasPrefix(text, "- ") && indent > 0 && len(stack) > 1 {
+				current := stack[len(stack)-1].node
+				if strings.HasPrefix(text, "Estimates:") {
+					current.Cost = strings.TrimSpace(strings.TrimPrefix(text, "Estimates:"))
+				} else {
+					current.Details = append(current.Details, text)
+				}
+
+				continue
+			}
+
+			for len(stack) > 1 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+
+			node := &planNode{Label: strings.TrimPrefix(text, "- ")}
+			parent := stack[len(stack)-1].node
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, level{indent: indent, node: node})
+		}
+	}
+
+	if len(root.Children) == 0 {
+		return nil, errEmptyPlan
+	}
+
+	return root, nil
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidFilter = errors.New("invalid filter expression")
+	errUnknownColumn = errors.New("unknown column")
+)
+
+// compareValues compares two normalized values by their type: NULL is less
+// than everything else, numbers are compared numerically, times
+// chronologically, and binary data bytewise. Values of other or mixed types
+// are compared by their text.
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if x, ok := numericValue(a, false); ok {
+		if y, ok := numericValue(b, false); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	switch x := a.(type) {
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1
+			case x.After(y):
+				return 1
+			default:
+				return 0
+			}
+		}
+	case []byte:
+		if y, ok := b.([]byte); ok {
+			return bytes.Compare(x, y)
+		}
+	}
+
+	return strings.Compare(valueString(a, ""), valueString(b, ""))
+}
+
+// numericValue returns the value of a number. Text is only parsed as number
+// if parseText is set.
+func numericValue(v interface{}, parseText bool) (float64, bool) {
+	switch value := v.(type) {
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	case json.Number:
+		f, err := value.Float64()
+
+		return f, err == nil
+	case string:
+		if !parseText {
+			return 0, false
+		}
+
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// rowFilter is a compiled filter expression like
+//
+//	price >= 10 AND (name LIKE 'a%' OR note IS NULL)
+//
+// Columns are compared with =, <> (or !=), <, <=, > and >= to strings or
+// numbers. LIKE ignores case. Conditions are combined with AND, OR and NOT.
+type rowFilter struct {
+	text  string
+	match func(row []interface{}) bool
+}
+
+type filterParser struct {
+	tokens  []sqlToken
+	pos     int
+	columns []string
+}
+
+// parseFilter compiles a filter expression for rows with the given columns.
+func parseFilter(text string, columns []string) (*rowFilter, error) {
+	p := &filterParser{columns: columns}
+
+	for _, tok := range tokenizeSQL(text) {
+		if tok.Kind != tokenWhitespace && tok.Kind != tokenComment {
+			p.tokens = append(p.tokens, tok)
+		}
+	}
+
+	match, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q: %w", p.tokens[p.pos].Text, errInvalidFilter)
+	}
+
+	return &rowFilter{text: text, match: match}, nil
+}
+
+// accept consumes the next token if it is text, ignoring case.
+func (p *filterParser) accept(text string) bool {
+	if p.pos < len(p.tokens) && strings.EqualFold(p.tokens[p.pos].Text, text) {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *filterParser) parseOr() (func([]interface{}) bool, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("OR") {
+		l := left
+
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = func(row []interface{}) bool { return l(row) || r(row) }
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (func([]interface{}) bool, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("AND") {
+		l := left
+
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = func(row []interface{}) bool { return l(row) && r(row) }
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (func([]interface{}) bool, error) {
+	if p.accept("NOT") {
+		cond, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(row []interface{}) bool { return !cond(row) }, nil
+	}
+
+	if p.accept("(") {
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing closing parenthesis: %w", errInvalidFilter)
+		}
+
+		return cond, nil
+	}
+
+	return p.parseCondition()
+}
+
+// parseCondition parses a comparison of a column with a value.
+func (p *filterParser) parseCondition() (func([]interface{}) bool, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("missing condition: %w", errInvalidFilter)
+	}
+
+	tok := p.tokens[p.pos]
+	if tok.Kind != tokenWord && tok.Kind != tokenQuotedIdent {
+		return nil, fmt.Errorf("expected column instead of %q: %w", tok.Text, errInvalidFilter)
+	}
+
+	p.pos++
+
+	col := -1
+
+	for idx, name := range p.columns {
+		if strings.EqualFold(name, unquoteIdentifier(tok.Text)) {
+			col = idx
+
+			break
+		}
+	}
+
+	if col < 0 {
+		return nil, fmt.Errorf("%s: %w", tok.Text, errUnknownColumn)
+	}
+
+	if p.accept("IS") {
+		not := p.accept("NOT")
+
+		if !p.accept("NULL") {
+			return nil, fmt.Errorf("expected NULL after IS: %w", errInvalidFilter)
+		}
+
+		return func(row []interface{}) bool { return (row[col] == nil) != not }, nil
+	}
+
+	not := p.accept("NOT")
+	if p.accept("LIKE") {
+		re, err := p.parseLikePattern()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(row []interface{}) bool {
+			return row[col] != nil && re.MatchString(valueString(row[col], "")) != not
+		}, nil
+	}
+
+	if not {
+		return nil, fmt.Errorf("expected LIKE after NOT: %w", errInvalidFilter)
+	}
+
+	op := p.parseOperator()
+	if op == "" {
+		return nil, fmt.Errorf("expected operator after %s: %w", tok.Text, errInvalidFilter)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(row []interface{}) bool {
+		if row[col] == nil {
+			return false
+		}
+
+		cmp := compareFilterValue(row[col], value)
+
+		switch op {
+		case "=":
+			return cmp == 0
+		case "<>", "!=":
+			return cmp != 0
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		default:
+			return cmp >= 0
+		}
+	}, nil
+}
+
+// parseOperator parses a comparison operator, which consists of one or two
+// punctuation tokens.
+func (p *filterParser) parseOperator() string {
+	for _, op := range []string{"<=", ">=", "<>", "!=", "=", "<", ">"} {
+		if p.pos+len(op) > len(p.tokens) {
+			continue
+		}
+
+		matches := true
+
+		for idx := 0; idx < len(op); idx++ {
+			if p.tokens[p.pos+idx].Text != op[idx:idx+1] {
+				matches = false
+			}
+		}
+
+		// the characters of the operator must not be separated by whitespace.
+		if matches && (len(op) == 1 || p.tokens[p.pos].End == p.tokens[p.pos+1].Start) {
+			p.pos += len(op)
+
+			return op
+		}
+	}
+
+	return ""
+}
+
+// parseValue parses a string or a number, which may be negative.
+func (p *filterParser) parseValue() (interface{}, error) {
+	negative := p.accept("-")
+
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("missing value: %w", errInvalidFilter)
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch {
+	case tok.Kind == tokenNumber:
+		f, err := strconv.ParseFloat(tok.Text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s: %w", tok.Text, errInvalidFilter)
+		}
+
+		if negative {
+			f = -f
+		}
+
+		return f, nil
+	case tok.Kind == tokenString && !negative:
+		return unquoteString(tok.Text), nil
+	default:
+		return nil, fmt.Errorf("expected value instead of %q: %w", tok.Text, errInvalidFilter)
+	}
+}
+
+// parseLikePattern parses the pattern of LIKE into a case-insensitive
+// regular expression.
+func (p *filterParser) parseLikePattern() (*regexp.Regexp, error) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].Kind != tokenString {
+		return nil, fmt.Errorf("expected string after LIKE: %w", errInvalidFilter)
+	}
+
+	pattern := unquoteString(p.tokens[p.pos].Text)
+	p.pos++
+
+	var sb strings.Builder
+
+	sb.WriteString("(?is)^")
+
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	sb.WriteString("$")
+
+	return regexp.Compile(sb.String())
+}
+
+// compareFilterValue compares a value of a row with a value of a filter
+// expression. Numbers are compared numerically if the row's value is a
+// number or text that looks like one.
+func compareFilterValue(v, filterValue interface{}) int {
+	if f, ok := filterValue.(float64); ok {
+		if x, ok := numericValue(v, true); ok {
+			switch {
+			case x < f:
+				return -1
+			case x > f:
+				return 1
+			default:
+				return 0
+			}
+		}
+
+		filterValue = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return strings.Compare(valueString(v, ""), filterValue.(string))
+}
+
+// unquoteString removes the quotes from a string literal.
+func unquoteString(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+
+	return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	columns := []string{"id", "name", "price", "note"}
+	rows := [][]interface{}{
+		{int64(1), "Apple", json.Number("1.50"), nil},
+		{int64(2), "banana", json.Number("0.25"), "ripe"},
+		{int64(3), "Cherry", json.Number("12"), "it's sweet"},
+		{int64(4), "date", nil, "10"},
+	}
+
+	tests := []struct {
+		filter string
+		want   []int64
+	}{
+		{filter: "id = 2", want: []int64{2}},
+		{filter: "id <> 2", want: []int64{1, 3, 4}},
+		{filter: "id != 2", want: []int64{1, 3, 4}},
+		{filter: "price >= 1.5", want: []int64{1, 3}},
+		{filter: "price < 1", want: []int64{2}},
+		{filter: "id > -1 AND id <= 2", want: []int64{1, 2}},
+		{filter: "name = 'Apple'", want: []int64{1}},
+		{filter: "name LIKE 'a%'", want: []int64{1}},
+		{filter: "name like '_a%'", want: []int64{2, 4}},
+		{filter: "name NOT LIKE '%e%'", want: []int64{2}},
+		{filter: "note IS NULL", want: []int64{1}},
+		{filter: "note IS NOT NULL", want: []int64{2, 3, 4}},
+		{filter: "note = 'it''s sweet'", want: []int64{3}},
+		{filter: "note = 10", want: []int64{4}},
+		{filter: "price > 1 OR note = 'ripe'", want: []int64{1, 2, 3}},
+		{filter: "NOT (id = 1 OR id = 2) AND price IS NOT NULL", want: []int64{3}},
+		{filter: `"NAME" = 'date'`, want: []int64{4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := parseFilter(tt.filter, columns)
+			if err != nil {
+				t.Fatalf("parseFilter returned error: %v", err)
+			}
+
+			var got []int64
+
+			for _, row := range rows {
+				if f.match(row) {
+					got = append(got, row[0].(int64))
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("filter matched ids %v, want %v", got, tt.want)
+			}
+
+			for idx := range got {
+				if got[idx] != tt.want[idx] {
+					t.Fatalf("filter matched ids %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   error
+	}{
+		{filter: "", want: errInvalidFilter},
+		{filter: "foo = 1", want: errUnknownColumn},
+		{filter: "id", want: errInvalidFilter},
+		{filter: "id = ", want: errInvalidFilter},
+		{filter: "id < = 1", want: errInvalidFilter},
+		{filter: "(id = 1", want: errInvalidFilter},
+		{filter: "id = 1 id = 2", want: errInvalidFilter},
+		{filter: "id IS 1", want: errInvalidFilter},
+		{filter: "id NOT = 1", want: errInvalidFilter},
+		{filter: "name LIKE 1", want: errInvalidFilter},
+		{filter: "name = -'a'", want: errInvalidFilter},
+		{filter: "1 = id", want: errInvalidFilter},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseFilter(tt.filter, []string{"id", "name"}); !errors.Is(err, tt.want) {
+			t.Errorf("parseFilter(%q) returned error %v, want %v", tt.filter, err, tt.want)
+		}
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	early := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		a, b interface{}
+		want int
+	}{
+		{a: nil, b: nil, want: 0},
+		{a: nil, b: int64(1), want: -1},
+		{a: "a", b: nil, want: 1},
+		{a: int64(2), b: int64(10), want: -1},
+		{a: json.Number("10.5"), b: int64(10), want: 1},
+		{a: 1.0, b: json.Number("1"), want: 0},
+		{a: "2", b: "10", want: 1},
+		{a: early, b: early.Add(time.Hour), want: -1},
+		{a: early, b: early, want: 0},
+		{a: []byte{0x01}, b: []byte{0x00, 0xff}, want: 1},
+		{a: "abc", b: "abd", want: -1},
+	}
+
+	for _, tt := range tests {
+		if got := compareValues(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareValues(%#v, %#v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,813 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	defaultPageSize  = 100
+	maxBinaryPreview = 16 // number of bytes of binary values shown in result table
+)
+
+// cellFormat renders normalized values as result table cells.
+type cellFormat struct {
+	TimeLayout string
+}
+
+var defaultCellFormat = cellFormat{TimeLayout: timeLayout}
+
+var errFetchCancelled = errors.New("fetching rows cancelled")
+
+// cell renders v as table cell. NULL values are shown dimmed, binary
+// values as truncated hex dump, and numbers are right-aligned.
+func (f cellFormat) cell(v interface{}) *tview.TableCell {
+	switch value := v.(type) {
+	case nil:
+		return tview.NewTableCell("NULL").SetTextColor(tcell.ColorGray).SetAttributes(tcell.AttrDim)
+	case []byte:
+		if len(value) <= maxBinaryPreview {
+			return tview.NewTableCell(fmt.Sprintf("\\x%x", value)).SetTextColor(tcell.ColorDarkCyan)
+		}
+
+		return tview.NewTableCell(fmt.Sprintf("\\x%x… (%d bytes)", value[:maxBinaryPreview], len(value))).SetTextColor(tcell.ColorDarkCyan)
+	case time.Time:
+		return tview.NewTableCell(value.Format(f.TimeLayout))
+	case int64, float64, json.Number:
+		return tview.NewTableCell(fmt.Sprint(value)).SetAlign(tview.AlignRight)
+	default:
+		return tview.NewTableCell(tview.Escape(valueString(value, "NULL")))
+	}
+}
+
+// resultTableContent implements tview.TableContent on top of an open query
+// result. Rows are fetched page by page as the user scrolls towards the end
+// of the already loaded rows. Results of statements that don't return rows
+// are shown as a single cell containing the result's message.
+type resultTableContent struct {
+	tview.TableContentReadOnly
+
+	result   *queryResult
+	pageSize int
+	format   cellFormat
+	onUpdate func() // called after a page has been fetched in the background
+
+	fetchMtx sync.Mutex // serializes access to result
+
+	mtx       sync.Mutex
+	rows      [][]interface{}
+	edit      *resultEdit // pending changes, nil if the result isn't being edited
+	fetching  bool
+	done      bool
+	cancelled bool // fetching was cancelled with cancelFetch
+	err       error
+
+	order      []int      // indexes of the shown rows if sorted or filtered, nil otherwise
+	sortColumn int        // -1 if the rows are in their original order
+	sortDesc   bool       // sorted in descending order
+	filter     *rowFilter // nil if all rows are shown
+	search     string     // lower-case text whose matches are highlighted
+
+	marked     bool // a selection of cells has been started
+	markRow    int  // row of the cell where the selection was started
+	markColumn int  // column of the cell where the selection was started
+	cursorRow  int  // row of the selected cell, which ends the selection
+	cursorCol  int  // column of the selected cell, which ends the selection
+}
+
+func newResultTableContent(result *queryResult, pageSize int, format cellFormat, onUpdate func()) *resultTableContent {
+	return &resultTableContent{
+		result:     result,
+		pageSize:   pageSize,
+		format:     format,
+		onUpdate:   onUpdate,
+		done:       result.rows == nil,
+		sortColumn: -1,
+	}
+}
+
+// fetchPage fetches the next page of rows from the result.
+func (c *resultTableContent) fetchPage() error {
+	c.fetchMtx.Lock()
+	defer c.fetchMtx.Unlock()
+
+	c.mtx.Lock()
+	done := c.done
+	c.mtx.Unlock()
+
+	if done {
+		return nil
+	}
+
+	rows, done, err := c.result.fetchRows(c.pageSize)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// drivers may still return rows after the query has been cancelled, and
+	// the errors that they report for a cancelled query vary.
+	if c.cancelled && (err != nil || !done) {
+		if !done {
+			c.result.close()
+		}
+
+		done, err = true, errFetchCancelled
+	}
+
+	c.rows = append(c.rows, rows...)
+	c.done = done
+	c.err = err
+	c.fetching = false
+
+	c.updateOrder()
+
+	return err
+}
+
+// fetchAll fetches all remaining rows from the result.
+func (c *resultTableContent) fetchAll() error {
+	for !c.isDone() {
+		if err := c.fetchPage(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *resultTableContent) fetchInBackground() {
+	if err := c.fetchPage(); err != nil {
+		log.Printf("Fetching rows failed: %v", err)
+	}
+
+	c.onUpdate()
+}
+
+// cancelFetch cancels the query of the result if not all rows have been
+// fetched yet, which also aborts a fetch that is in progress. Fetching then
+// fails with errFetchCancelled. It returns whether there was anything to
+// cancel.
+func (c *resultTableContent) cancelFetch() bool {
+	c.mtx.Lock()
+	if c.done || c.result.cancel == nil {
+		c.mtx.Unlock()
+
+		return false
+	}
+
+	c.cancelled = true
+	c.mtx.Unlock()
+
+	c.result.cancel()
+
+	return true
+}
+
+func (c *resultTableContent) isDone() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.done
+}
+
+// close closes the underlying result. Rows that have already been fetched
+// remain available.
+func (c *resultTableContent) close() {
+	c.fetchMtx.Lock()
+	defer c.fetchMtx.Unlock()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !c.done {
+		c.result.close()
+		c.done = true
+	}
+}
+
+// status returns a short description of how many rows have been loaded.
+func (c *resultTableContent) status() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	switch {
+	case c.result.rows == nil:
+		return c.result.message
+	case errors.Is(c.err, errFetchCancelled):
+		return fmt.Sprintf("%d rows, fetching more cancelled", len(c.rows))
+	case c.err != nil:
+		return fmt.Sprintf("%d rows, fetching more failed: %v", len(c.rows), c.err)
+	case !c.done:
+		return fmt.Sprintf("%d+ rows", len(c.rows)) + c.viewStatus() + c.editStatus()
+	case len(c.rows) == 1:
+		return "1 row" + c.viewStatus() + c.editStatus()
+	default:
+		return fmt.Sprintf("%d rows", len(c.rows)) + c.viewStatus() + c.editStatus()
+	}
+}
+
+// viewStatus describes how the rows are filtered and sorted. c.mtx must be
+// held.
+func (c *resultTableContent) viewStatus() string {
+	var status string
+
+	if c.filter != nil {
+		status += fmt.Sprintf(", %d shown", len(c.order))
+	}
+
+	if c.sortColumn >= 0 {
+		status += ", sorted by " + c.result.columns[c.sortColumn]
+		if c.sortDesc {
+			status += " descending"
+		}
+	}
+
+	return status
+}
+
+// editStatus returns the number of pending changes, if any. c.mtx must be
+// held.
+func (c *resultTableContent) editStatus() string {
+	if c.edit == nil {
+		return ""
+	}
+
+	if n := c.edit.pending(); n > 0 {
+		return fmt.Sprintf(", %d pending changes", n)
+	}
+
+	return ""
+}
+
+func (c *resultTableContent) GetCell(row, column int) *tview.TableCell {
+	if c.result.rows == nil {
+		if row != 0 || column != 0 {
+			return nil
+		}
+
+		return tview.NewTableCell(c.result.message)
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if row == 0 {
+		if column >= len(c.result.columns) {
+			return nil
+		}
+
+		text := c.result.columns[column]
+
+		if column == c.sortColumn {
+			if c.sortDesc {
+				text += " ▼"
+			} else {
+				text += " ▲"
+			}
+		}
+
+		return tview.NewTableCell(text).SetAttributes(tcell.AttrBold).SetSelectable(false)
+	}
+
+	if !c.done && !c.fetching && row >= c.shownRows()-c.pageSize/2 {
+		c.fetching = true
+
+		go c.fetchInBackground()
+	}
+
+	idx := c.rowIndex(row)
+	if idx < 0 || column >= len(c.result.columns) {
+		return nil
+	}
+
+	value := c.value(idx, column)
+	cell := c.format.cell(value)
+
+	if c.search != "" && strings.Contains(strings.ToLower(c.text(value)), c.search) {
+		cell.SetBackgroundColor(tcell.ColorYellow).SetTextColor(tcell.ColorBlack)
+	} else if c.isMarked(row, column) {
+		cell.SetBackgroundColor(tcell.ColorNavy)
+	}
+
+	if c.edit != nil {
+		if edit := c.rowEdit(idx); edit != nil {
+			return editedCell(cell, edit, column, idx >= len(c.rows))
+		}
+	}
+
+	return cell
+}
+
+// editedCell highlights a cell of a row that has pending changes.
+func editedCell(cell *tview.TableCell, edit *rowEdit, column int, inserted bool) *tview.TableCell {
+	switch {
+	case edit.deleted:
+		return cell.SetBackgroundColor(tcell.ColorDarkRed).SetAttributes(tcell.AttrStrikeThrough)
+	case edit.changed[column]:
+		return cell.SetBackgroundColor(tcell.ColorOlive)
+	case inserted:
+		return cell.SetBackgroundColor(tcell.ColorDarkGreen)
+	default:
+		return cell
+	}
+}
+
+// shownRows returns the number of fetched rows that are shown. c.mtx must
+// be held.
+func (c *resultTableContent) shownRows() int {
+	if c.order != nil {
+		return len(c.order)
+	}
+
+	return len(c.rows)
+}
+
+// rowIndex returns the index into c.rows of a table row, taking sorting and
+// filtering into account. Indexes after the fetched rows refer to inserted
+// rows, which are shown after the fetched rows. It returns -1 if there is no
+// such row. c.mtx must be held.
+func (c *resultTableContent) rowIndex(row int) int {
+	idx := row - 1
+	shown := c.shownRows()
+
+	switch {
+	case idx < 0:
+		return -1
+	case idx < shown && c.order != nil:
+		return c.order[idx]
+	case idx < shown:
+		return idx
+	case c.edit != nil && idx-shown < len(c.edit.inserted):
+		return len(c.rows) + idx - shown
+	default:
+		return -1
+	}
+}
+
+// value returns the value of a cell including pending changes. c.mtx must
+// be held.
+func (c *resultTableContent) value(idx, column int) interface{} {
+	if c.edit != nil {
+		if edit := c.rowEdit(idx); edit != nil {
+			return edit.values[column]
+		}
+	}
+
+	return c.rows[idx][column]
+}
+
+// text returns the text of a value as it is shown in the table.
+func (c *resultTableContent) text(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(c.format.TimeLayout)
+	}
+
+	return valueString(v, "NULL")
+}
+
+// rowEdit returns the pending changes of the row with index idx, where
+// indexes after the fetched rows refer to inserted rows. c.mtx must be held
+// and c.edit must be set.
+func (c *resultTableContent) rowEdit(idx int) *rowEdit {
+	if idx >= len(c.rows) {
+		if idx-len(c.rows) < len(c.edit.inserted) {
+			return c.edit.inserted[idx-len(c.rows)]
+		}
+
+		return nil
+	}
+
+	return c.edit.rows[idx]
+}
+
+// updateOrder determines the shown rows and their order from the filter and
+// the sort column. The original order of the rows is kept in c.rows. c.mtx
+// must be held.
+func (c *resultTableContent) updateOrder() {
+	if c.filter == nil && c.sortColumn < 0 {
+		c.order = nil
+
+		return
+	}
+
+	order := make([]int, 0, len(c.rows))
+
+	for idx, row := range c.rows {
+		if c.filter == nil || c.filter.match(row) {
+			order = append(order, idx)
+		}
+	}
+
+	if col := c.sortColumn; col >= 0 {
+		sort.SliceStable(order, func(i, j int) bool {
+			cmp := compareValues(c.rows[order[i]][col], c.rows[order[j]][col])
+			if c.sortDesc {
+				return cmp > 0
+			}
+
+			return cmp < 0
+		})
+	}
+
+	c.order = order
+}
+
+// sortBy sorts the rows by a column. Sorting by the same column again
+// reverses the order, and sorting by it a third time restores the original
+// order.
+func (c *resultTableContent) sortBy(column int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	switch {
+	case column < 0 || column >= len(c.result.columns):
+		return
+	case column != c.sortColumn:
+		c.sortColumn, c.sortDesc = column, false
+	case !c.sortDesc:
+		c.sortDesc = true
+	default:
+		c.sortColumn, c.sortDesc = -1, false
+	}
+
+	c.updateOrder()
+}
+
+// setFilter shows only the rows that match filter, or all rows if filter is
+// nil.
+func (c *resultTableContent) setFilter(filter *rowFilter) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.filter = filter
+	c.updateOrder()
+}
+
+// filterText returns the expression of the current filter.
+func (c *resultTableContent) filterText() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.filter == nil {
+		return ""
+	}
+
+	return c.filter.text
+}
+
+// setSearch highlights the cells that contain text, ignoring case.
+func (c *resultTableContent) setSearch(text string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.search = strings.ToLower(text)
+}
+
+// findMatch returns the next cell after the given one that contains the
+// search text, or the previous one if backwards is set. The search wraps
+// around at the end of the table. If includeCurrent is set, the given cell
+// itself is a match, too.
+func (c *resultTableContent) findMatch(row, column int, backwards, includeCurrent bool) (int, int, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	columns := len(c.result.columns)
+	if c.search == "" || c.result.rows == nil || columns == 0 {
+		return 0, 0, false
+	}
+
+	rows := c.shownRows()
+	if c.edit != nil {
+		rows += len(c.edit.inserted)
+	}
+
+	total := rows * columns
+	if total == 0 {
+		return 0, 0, false
+	}
+
+	pos := (row-1)*columns + column
+	if pos < 0 {
+		pos = -1
+	}
+
+	step := 1
+	if backwards {
+		step = -1
+	}
+
+	start := 1
+	if includeCurrent {
+		start = 0
+	}
+
+	for n := start; n <= total; n++ {
+		p := ((pos+n*step)%total + total) % total
+		r, col := p/columns+1, p%columns
+
+		if strings.Contains(strings.ToLower(c.text(c.value(c.rowIndex(r), col))), c.search) {
+			return r, col, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// rowValues returns the current values of a row of the table, including
+// pending changes.
+func (c *resultTableContent) rowValues(row int) ([]interface{}, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	idx := c.rowIndex(row)
+	if idx < 0 {
+		return nil, false
+	}
+
+	values := make([]interface{}, len(c.result.columns))
+	for col := range values {
+		values[col] = c.value(idx, col)
+	}
+
+	return values, true
+}
+
+// toggleMark starts a selection of cells at the given cell, which extends
+// to the selected cell, or ends the selection if one has been started.
+func (c *resultTableContent) toggleMark(row, column int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.marked = !c.marked
+	c.markRow, c.markColumn = row, column
+	c.cursorRow, c.cursorCol = row, column
+}
+
+// moveCursor updates the end of the selection of cells.
+func (c *resultTableContent) moveCursor(row, column int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.cursorRow, c.cursorCol = row, column
+}
+
+// selection returns the rows and columns of the selected cells, which is
+// only the selected cell if no selection has been started.
+func (c *resultTableContent) selection() (top, left, bottom, right int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !c.marked {
+		return c.cursorRow, c.cursorCol, c.cursorRow, c.cursorCol
+	}
+
+	return c.markedRange()
+}
+
+// markedRange returns the rows and columns of the selected cells. c.mtx
+// must be held.
+func (c *resultTableContent) markedRange() (top, left, bottom, right int) {
+	top, bottom = c.markRow, c.cursorRow
+	if top > bottom {
+		top, bottom = bottom, top
+	}
+
+	left, right = c.markColumn, c.cursorCol
+	if left > right {
+		left, right = right, left
+	}
+
+	return top, left, bottom, right
+}
+
+// isMarked returns whether a cell is part of the selection. c.mtx must be
+// held.
+func (c *resultTableContent) isMarked(row, column int) bool {
+	if !c.marked {
+		return false
+	}
+
+	top, left, bottom, right := c.markedRange()
+
+	return row >= top && row <= bottom && column >= left && column <= right
+}
+
+// cellRange returns the columns and the current values of a rectangle of
+// cells of the table, in the order in which they are shown. Rows that
+// haven't been fetched yet are left out.
+func (c *resultTableContent) cellRange(top, left, bottom, right int) ([]string, [][]interface{}) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if left < 0 {
+		left = 0
+	}
+
+	if right >= len(c.result.columns) {
+		right = len(c.result.columns) - 1
+	}
+
+	if left > right {
+		return nil, nil
+	}
+
+	var rows [][]interface{}
+
+	for row := top; row <= bottom; row++ {
+		idx := c.rowIndex(row)
+		if idx < 0 {
+			continue
+		}
+
+		values := make([]interface{}, 0, right-left+1)
+		for col := left; col <= right; col++ {
+			values = append(values, c.value(idx, col))
+		}
+
+		rows = append(rows, values)
+	}
+
+	return c.result.columns[left : right+1], rows
+}
+
+// lastRow returns the last row of the table.
+func (c *resultTableContent) lastRow() int {
+	return c.GetRowCount() - 1
+}
+
+// editable returns whether the result can be edited, and the table that its
+// rows belong to.
+func (c *resultTableContent) editable() (string, bool) {
+	return c.result.table, c.result.table != "" && c.result.rows != nil
+}
+
+func (c *resultTableContent) startEdit(edit *resultEdit) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.edit == nil {
+		c.edit = edit
+	}
+}
+
+func (c *resultTableContent) isEditing() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.edit != nil
+}
+
+// cellValue returns the current value of a cell of the table, including
+// pending changes.
+func (c *resultTableContent) cellValue(row, column int) (interface{}, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	idx := c.rowIndex(row)
+	if idx < 0 || column < 0 || column >= len(c.result.columns) {
+		return nil, false
+	}
+
+	return c.value(idx, column), true
+}
+
+// setCellValue changes the value of a cell of the table. It fails if the
+// column isn't a column of the edited table or if the row is deleted.
+func (c *resultTableContent) setCellValue(row, column int, value interface{}) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	idx := c.rowIndex(row)
+	if c.edit == nil || idx < 0 || column < 0 || column >= len(c.result.columns) {
+		return errNotEditable
+	}
+
+	if !c.edit.editable[column] {
+		return fmt.Errorf("%s is not a column of %s: %w", c.result.columns[column], c.edit.table, errNotEditable)
+	}
+
+	edit := c.rowEdit(idx)
+	if edit == nil {
+		edit = c.edit.row(idx, c.rows[idx])
+	}
+
+	if edit.deleted {
+		return fmt.Errorf("row is deleted: %w", errNotEditable)
+	}
+
+	edit.values[column] = value
+	edit.changed[column] = true
+
+	return nil
+}
+
+// insertRow adds an empty row to the table and returns its table row.
+func (c *resultTableContent) insertRow() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.edit.insertRow()
+
+	return c.shownRows() + len(c.edit.inserted)
+}
+
+// toggleDeleted marks a row as deleted, or unmarks it. Inserted rows are
+// removed right away.
+func (c *resultTableContent) toggleDeleted(row int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.edit == nil {
+		return
+	}
+
+	idx := c.rowIndex(row)
+
+	switch {
+	case idx < 0:
+	case idx >= len(c.rows):
+		ins := idx - len(c.rows)
+		c.edit.inserted = append(c.edit.inserted[:ins], c.edit.inserted[ins+1:]...)
+	default:
+		edit := c.edit.row(idx, c.rows[idx])
+		edit.deleted = !edit.deleted
+	}
+}
+
+// editStatements returns the statements that apply the pending changes.
+func (c *resultTableContent) editStatements(quote func(string) string) []editStatement {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.edit == nil {
+		return nil
+	}
+
+	return c.edit.statements(quote, func(idx int) []interface{} {
+		return c.rows[idx]
+	})
+}
+
+// commitEdit applies the pending changes to the fetched rows after they
+// have been written to the database.
+func (c *resultTableContent) commitEdit() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.edit != nil {
+		c.rows = c.edit.apply(c.rows)
+		c.updateOrder()
+	}
+}
+
+// discardEdit discards all pending changes.
+func (c *resultTableContent) discardEdit() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.edit != nil {
+		c.edit.rows = make(map[int]*rowEdit)
+		c.edit.inserted = nil
+	}
+}
+
+// allRows returns the rows that have been fetched so far, in their original
+// order.
+func (c *resultTableContent) allRows() [][]interface{} {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.rows
+}
+
+func (c *resultTableContent) GetRowCount() int {
+	if c.result.rows == nil {
+		return 1
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.edit != nil {
+		return c.shownRows() + len(c.edit.inserted) + 1
+	}
+
+	return c.shownRows() + 1
+}
+
+func (c *resultTableContent) GetColumnCount() int {
+	if c.result.rows == nil {
+		return 1
+	}
+
+	return len(c.result.columns)
+}
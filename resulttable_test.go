@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCancelFetch(t *testing.T) {
+	m, dbID := openTestDatabase(t)
+
+	if _, err := m.dbInfo[dbID].Conn().Exec("WITH RECURSIVE n(i) AS (SELECT 3 UNION ALL SELECT i + 1 FROM n WHERE i < 500) INSERT INTO t SELECT i, 'x' FROM n"); err != nil {
+		t.Fatal(err)
+	}
+
+	stmts := splitStatements("SELECT * FROM t", sqliteDialect)
+
+	result, err := m.execStatement(context.Background(), m.dbInfo[dbID], dbID, stmts[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := newResultTableContent(result, 100, defaultCellFormat, func() {})
+
+	if err := content.fetchPage(); err != nil {
+		t.Fatalf("fetchPage returned error: %v", err)
+	}
+
+	if !content.cancelFetch() {
+		t.Fatalf("cancelFetch returned false for a partially fetched result")
+	}
+
+	if err := content.fetchAll(); !errors.Is(err, errFetchCancelled) {
+		t.Errorf("fetchAll after cancelFetch returned error %v, want %v", err, errFetchCancelled)
+	}
+
+	if status := content.status(); !strings.Contains(status, "cancelled") {
+		t.Errorf("status = %q, want it to mention the cancellation", status)
+	}
+
+	if content.cancelFetch() {
+		t.Errorf("cancelFetch returned true for a result that is done")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import "errors"
+
+var errNoDDL = errors.New("no DDL available")
+
+// objectKind is a kind of database object that is shown in the database
+// tree.
+type objectKind int
+
+const (
+	objectTables objectKind = iota
+	objectViews
+	objectMaterializedViews
+	objectFunctions
+	objectSequences
+	objectIndexes
+	objectForeignKeys
+	objectTriggers
+)
+
+func (k objectKind) String() string {
+	switch k {
+	case objectTables:
+		return "Tables"
+	case objectViews:
+		return "Views"
+	case objectMaterializedViews:
+		return "Materialized Views"
+	case objectFunctions:
+		return "Functions"
+	case objectSequences:
+		return "Sequences"
+	case objectIndexes:
+		return "Indexes"
+	case objectForeignKeys:
+		return "Foreign Keys"
+	case objectTriggers:
+		return "Triggers"
+	default:
+		return "Objects"
+	}
+}
+
+// hasColumns returns whether objects of the kind have columns.
+func (k objectKind) hasColumns() bool {
+	return k == objectTables || k == objectViews || k == objectMaterializedViews
+}
+
+// tableObjectKinds are the kinds of objects that belong to a table.
+var tableObjectKinds = []objectKind{objectIndexes, objectForeignKeys, objectTriggers}
+
+// schemaObject is an object of a database schema, such as a table or an
+// index.
+type schemaObject struct {
+	Name   string
+	Detail string // e.g. the columns of an index, empty if there are none
+}
+
+// schemaBrowser is implemented by the dbInfo of drivers that can list the
+// schemas of a database and the objects in them, beyond tables and columns.
+type schemaBrowser interface {
+	GetSchemas() ([]string, error)
+	// SchemaObjectKinds returns the kinds of objects that GetSchemaObjects
+	// can list.
+	SchemaObjectKinds() []objectKind
+	GetSchemaObjects(schema string, kind objectKind) ([]schemaObject, error)
+	GetColumns(schema, table string) ([]column, error)
+	// GetTableObjects lists the objects of one of the tableObjectKinds.
+	GetTableObjects(schema, table string, kind objectKind) ([]schemaObject, error)
+}
+
+// ddlProvider is implemented by the dbInfo of drivers that can show the DDL
+// of tables, views and indexes.
+type ddlProvider interface {
+	GetDDL(schema, name string, kind objectKind) (string, error)
+}
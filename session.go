@@ -13,13 +13,26 @@ type sessionData struct {
 }
 
 type sessionDataDB struct {
+	Profile       string            `yaml:"profile,omitempty"`
 	Driver        string            `yaml:"driver"`
 	ConnectParams map[string]string `yaml:"connect_params"`
 }
 
 type queriesData struct {
-	Tabs  []string `yaml:"tabs"`
-	Index int      `yaml:"index"`
+	Tabs   []string            `yaml:"tabs"`
+	Index  int                 `yaml:"index"`
+	Names  []string            `yaml:"names,omitempty"`  // names of the tabs
+	DBs    []queryTabDB        `yaml:"dbs,omitempty"`    // databases that the tabs are bound to
+	Params []map[string]string `yaml:"params,omitempty"` // last parameter values by tab
+}
+
+// queryTabDB identifies the database that a query tab is bound to across
+// sessions: by the profile it was opened from, or else by its connection,
+// which consists of the driver and the connect parameters that aren't
+// secret.
+type queryTabDB struct {
+	Profile    string `yaml:"profile,omitempty"`
+	Connection string `yaml:"connection,omitempty"`
 }
 
 func loadSession(filename string) (*sessionData, error) {
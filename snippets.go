@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	errInvalidSnippetPath = errors.New("snippet path must be relative to the snippet directory")
+	errReadingSnippets    = errors.New("reading snippets failed")
+)
+
+// snippet is a query stored in a .sql file of the snippet library. The file
+// may start with front-matter in SQL comments, so that it stays valid SQL:
+//
+//	-- ---
+//	-- name: Active users
+//	-- description: Users that logged in within the last 30 days
+//	-- driver: postgres
+//	-- profile: production
+//	-- ---
+//	SELECT * FROM users WHERE last_login > now() - interval '30 days';
+type snippet struct {
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Driver      string `yaml:"driver,omitempty"`  // driver that the query is written for
+	Profile     string `yaml:"profile,omitempty"` // connection profile that the query is run on
+
+	Path  string `yaml:"-"` // path of the file relative to the snippet directory
+	Query string `yaml:"-"`
+}
+
+// title returns the name of the snippet, or its file name if it has no
+// name.
+func (s snippet) title() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return strings.TrimSuffix(filepath.Base(s.Path), ".sql")
+}
+
+const (
+	frontMatterDelimiter = "-- ---"
+	frontMatterPrefix    = "-- "
+)
+
+// parseSnippet parses the content of a snippet file.
+func parseSnippet(path, content string) (snippet, error) {
+	s := snippet{Path: path, Query: content}
+
+	if !strings.HasPrefix(content, frontMatterDelimiter+"\n") {
+		return s, nil
+	}
+
+	var (
+		frontMatter strings.Builder
+		offset      = len(frontMatterDelimiter) + 1
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(content[offset:]))
+	for scanner.Scan() {
+		line := scanner.Text()
+		offset += len(line) + 1
+
+		if strings.TrimSpace(line) == frontMatterDelimiter {
+			if err := yaml.Unmarshal([]byte(frontMatter.String()), &s); err != nil {
+				return s, fmt.Errorf("invalid front-matter in %s: %w", path, err)
+			}
+
+			if offset > len(content) {
+				offset = len(content)
+			}
+
+			s.Query = strings.TrimLeft(content[offset:], "\n")
+
+			return s, nil
+		}
+
+		frontMatter.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, frontMatterPrefix), "--"))
+		frontMatter.WriteByte('\n')
+	}
+
+	// without a closing delimiter, the comment is part of the query.
+	return s, nil
+}
+
+// content returns the file content of the snippet, with front-matter if
+// any of its fields are set.
+func (s snippet) content() (string, error) {
+	query := strings.TrimRight(s.Query, "\n") + "\n"
+
+	if s.Name == "" && s.Description == "" && s.Driver == "" && s.Profile == "" {
+		return query, nil
+	}
+
+	frontMatter, err := yaml.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("marshalling front-matter failed: %w", err)
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(frontMatterDelimiter + "\n")
+
+	for _, line := range strings.Split(strings.TrimRight(string(frontMatter), "\n"), "\n") {
+		sb.WriteString(frontMatterPrefix + line + "\n")
+	}
+
+	sb.WriteString(frontMatterDelimiter + "\n")
+	sb.WriteString(query)
+
+	return sb.String(), nil
+}
+
+// snippetLibrary is a directory of .sql files, which may be organized in
+// subdirectories.
+type snippetLibrary struct {
+	dir string
+}
+
+func newSnippetLibrary(dir string) *snippetLibrary {
+	return &snippetLibrary{dir: dir}
+}
+
+// snippetErrors are the errors of all snippet files that couldn't be read.
+// They match errReadingSnippets with errors.Is.
+type snippetErrors []error
+
+func (e snippetErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return errReadingSnippets.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e snippetErrors) Is(target error) bool {
+	return target == errReadingSnippets
+}
+
+// list returns all snippets ordered by path. Files that can't be read are
+// skipped and reported in the returned error of type snippetErrors.
+func (l *snippetLibrary) list() ([]snippet, error) {
+	var (
+		snippets []snippet
+		errs     snippetErrors
+	)
+
+	err := filepath.WalkDir(l.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == l.dir {
+				return filepath.SkipDir
+			}
+
+			return err
+		}
+
+		if d.IsDir() {
+			if path != l.dir && strings.HasPrefix(d.Name(), ".") { // e.g. .git
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if filepath.Ext(path) != ".sql" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.dir, path)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+
+			return nil
+		}
+
+		s, err := parseSnippet(filepath.ToSlash(rel), string(data))
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		snippets = append(snippets, s)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing snippets in %s failed: %w", l.dir, err)
+	}
+
+	sort.Slice(snippets, func(i, j int) bool {
+		return snippets[i].Path < snippets[j].Path
+	})
+
+	if len(errs) > 0 {
+		return snippets, errs
+	}
+
+	return snippets, nil
+}
+
+// save writes a snippet to the file at its path, creating directories as
+// necessary. The extension .sql is added to the path if it is missing.
+func (l *snippetLibrary) save(s snippet) (snippet, error) {
+	path := filepath.Clean(filepath.FromSlash(s.Path))
+	if filepath.IsAbs(path) || path == "." || strings.HasPrefix(path, "..") {
+		return s, fmt.Errorf("%s: %w", s.Path, errInvalidSnippetPath)
+	}
+
+	if filepath.Ext(path) != ".sql" {
+		path += ".sql"
+	}
+
+	s.Path = filepath.ToSlash(path)
+
+	content, err := s.content()
+	if err != nil {
+		return s, err
+	}
+
+	filename := filepath.Join(l.dir, path)
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return s, fmt.Errorf("creating snippet directory failed: %w", err)
+	}
+
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		return s, fmt.Errorf("writing snippet %s failed: %w", filename, err)
+	}
+
+	return s, nil
+}
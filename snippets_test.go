@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    snippet
+	}{
+		{
+			name:    "without front-matter",
+			content: "SELECT 1;\n",
+			want:    snippet{Path: "a.sql", Query: "SELECT 1;\n"},
+		},
+		{
+			name:    "with front-matter",
+			content: "-- ---\n-- name: Active users\n-- description: Logged in recently\n-- driver: postgres\n-- ---\n\nSELECT * FROM users;\n",
+			want: snippet{
+				Name:        "Active users",
+				Description: "Logged in recently",
+				Driver:      "postgres",
+				Path:        "a.sql",
+				Query:       "SELECT * FROM users;\n",
+			},
+		},
+		{
+			name:    "unterminated front-matter is part of the query",
+			content: "-- ---\n-- name: x\nSELECT 1;\n",
+			want:    snippet{Path: "a.sql", Query: "-- ---\n-- name: x\nSELECT 1;\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSnippet("a.sql", tt.content)
+			if err != nil {
+				t.Fatalf("parseSnippet returned error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseSnippet = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippetContentRoundTrip(t *testing.T) {
+	for _, s := range []snippet{
+		{Path: "plain.sql", Query: "SELECT 1;\n"},
+		{Path: "dir/meta.sql", Name: "Meta", Description: "With: colons and # hashes", Profile: "production", Query: "SELECT 2;\n"},
+	} {
+		content, err := s.content()
+		if err != nil {
+			t.Fatalf("content of %s returned error: %v", s.Path, err)
+		}
+
+		got, err := parseSnippet(s.Path, content)
+		if err != nil {
+			t.Fatalf("parsing %s returned error: %v", s.Path, err)
+		}
+
+		if got != s {
+			t.Errorf("round trip of %+v returned %+v", s, got)
+		}
+	}
+}
+
+func TestSnippetLibrary(t *testing.T) {
+	dir := t.TempDir()
+	lib := newSnippetLibrary(filepath.Join(dir, "snippets"))
+
+	snippets, err := lib.list()
+	if err != nil || len(snippets) != 0 {
+		t.Fatalf("list of missing directory = %v, %v, want no snippets and no error", snippets, err)
+	}
+
+	saved, err := lib.save(snippet{Path: "reports/daily", Name: "Daily", Query: "SELECT 1"})
+	if err != nil {
+		t.Fatalf("save returned error: %v", err)
+	}
+
+	if saved.Path != "reports/daily.sql" {
+		t.Errorf("saved snippet has path %s, want reports/daily.sql", saved.Path)
+	}
+
+	for _, path := range []string{"/etc/passwd", "../outside.sql", "."} {
+		if _, err := lib.save(snippet{Path: path}); !errors.Is(err, errInvalidSnippetPath) {
+			t.Errorf("save(%q) returned error %v, want %v", path, err, errInvalidSnippetPath)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "snippets", "broken.sql"), []byte("-- ---\n-- name: [\n-- ---\nSELECT 2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	snippets, err = lib.list()
+	if !errors.Is(err, errReadingSnippets) {
+		t.Errorf("list returned error %v, want %v", err, errReadingSnippets)
+	}
+
+	var errs snippetErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Errorf("list returned error %#v, want one snippet error", err)
+	}
+
+	if len(snippets) != 2 || snippets[0].Path != "broken.sql" || snippets[1].title() != "Daily" {
+		t.Errorf("list returned %+v", snippets)
+	}
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type sqlTokenKind int
+
+const (
+	tokenWhitespace sqlTokenKind = iota
+	tokenComment
+	tokenString
+	tokenQuotedIdent
+	tokenNumber
+	tokenWord
+	tokenPunct
+)
+
+// sqlToken is a token of an SQL text. Start and End are byte offsets into
+// the tokenized text.
+type sqlToken struct {
+	Kind  sqlTokenKind
+	Text  string
+	Start int
+	End   int
+}
+
+// sqlDialect describes the lexical differences between the SQL dialects of
+// the supported databases.
+type sqlDialect struct {
+	DollarQuotes     bool // PostgreSQL dollar-quoted strings like $$...$$ or $body$...$body$
+	BacktickIdents   bool // identifiers quoted in backticks
+	HashComments     bool // comments starting with # until the end of the line
+	BackslashEscapes bool // backslash escapes within strings like 'it\'s'
+	DoubleQuotedStrs bool // "..." is a string rather than a quoted identifier
+	BlockBodies      bool // BEGIN ... END bodies of CREATE TRIGGER and the like contain semicolons
+	DollarParams     bool // query parameters are written as $1, $2 and so on rather than ?
+	LowerCaseIdents  bool // unquoted identifiers are folded to lower case
+}
+
+// genericDialect accepts the quirks of all supported databases that don't
+// conflict with each other.
+var genericDialect = sqlDialect{DollarQuotes: true, BacktickIdents: true, BlockBodies: true}
+
+// tokenizeSQL splits s into tokens using the generic dialect.
+func tokenizeSQL(s string) []sqlToken {
+	return tokenizeDialect(s, genericDialect)
+}
+
+// tokenizeDialect splits s into tokens. It is deliberately lenient: it
+// never fails, and unterminated strings or comments simply extend to the end
+// of s.
+func tokenizeDialect(s string, dialect sqlDialect) []sqlToken {
+	var tokens []sqlToken
+
+	for pos := 0; pos < len(s); {
+		kind, end := scanToken(s, pos, dialect)
+		tokens = append(tokens, sqlToken{Kind: kind, Text: s[pos:end], Start: pos, End: end})
+		pos = end
+	}
+
+	return tokens
+}
+
+func scanToken(s string, pos int, dialect sqlDialect) (sqlTokenKind, int) {
+	c := s[pos]
+
+	switch {
+	case isSpace(c):
+		end := pos + 1
+		for end < len(s) && isSpace(s[end]) {
+			end++
+		}
+
+		return tokenWhitespace, end
+	case strings.HasPrefix(s[pos:], "--"), dialect.HashComments && c == '#':
+		end := strings.IndexByte(s[pos:], '\n')
+		if end < 0 {
+			return tokenComment, len(s)
+		}
+
+		return tokenComment, pos + end
+	case strings.HasPrefix(s[pos:], "/*"):
+		end := strings.Index(s[pos+2:], "*/")
+		if end < 0 {
+			return tokenComment, len(s)
+		}
+
+		return tokenComment, pos + 2 + end + 2
+	case c == '\'':
+		return tokenString, scanQuoted(s, pos, '\'', dialect.BackslashEscapes)
+	case c == '"' && dialect.DoubleQuotedStrs:
+		return tokenString, scanQuoted(s, pos, '"', dialect.BackslashEscapes)
+	case c == '"':
+		return tokenQuotedIdent, scanQuoted(s, pos, '"', false)
+	case c == '`' && dialect.BacktickIdents:
+		return tokenQuotedIdent, scanQuoted(s, pos, '`', false)
+	case c == '$' && dialect.DollarQuotes:
+		if tag := dollarQuoteTag(s[pos:]); tag != "" {
+			end := strings.Index(s[pos+len(tag):], tag)
+			if end < 0 {
+				return tokenString, len(s)
+			}
+
+			return tokenString, pos + len(tag) + end + len(tag)
+		}
+
+		return tokenPunct, pos + 1
+	case isDigit(c) || (c == '.' && pos+1 < len(s) && isDigit(s[pos+1])):
+		return tokenNumber, scanNumber(s, pos)
+	case isWordStart(c):
+		end := pos + 1
+		for end < len(s) && isWordChar(s[end]) {
+			end++
+		}
+
+		return tokenWord, end
+	default:
+		_, size := utf8.DecodeRuneInString(s[pos:])
+
+		return tokenPunct, pos + size
+	}
+}
+
+// scanQuoted returns the end of the quoted string starting at pos. A
+// doubled quote character is treated as an escaped quote, as is a quote
+// preceded by a backslash if backslashEsc is set.
+func scanQuoted(s string, pos int, quote byte, backslashEsc bool) int {
+	for i := pos + 1; i < len(s); i++ {
+		if backslashEsc && s[i] == '\\' {
+			i++
+
+			continue
+		}
+
+		if s[i] != quote {
+			continue
+		}
+
+		if i+1 < len(s) && s[i+1] == quote {
+			i++
+
+			continue
+		}
+
+		return i + 1
+	}
+
+	return len(s)
+}
+
+// dollarQuoteTag returns the PostgreSQL dollar quote tag (e.g. $$ or
+// $body$) that s starts with, or an empty string if there is none.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '$':
+			return s[:i+1]
+		case i == 1 && isDigit(s[i]), !isWordChar(s[i]):
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func scanNumber(s string, pos int) int {
+	end := pos
+	for end < len(s) && (isDigit(s[end]) || s[end] == '.') {
+		end++
+	}
+
+	if end < len(s) && (s[end] == 'e' || s[end] == 'E') {
+		exp := end + 1
+		if exp < len(s) && (s[exp] == '+' || s[exp] == '-') {
+			exp++
+		}
+
+		if exp < len(s) && isDigit(s[exp]) {
+			end = exp
+			for end < len(s) && isDigit(s[end]) {
+				end++
+			}
+		}
+	}
+
+	return end
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= utf8.RuneSelf
+}
+
+func isWordChar(c byte) bool {
+	return isWordStart(c) || isDigit(c) || c == '$'
+}
+
+// sqlStatement is a single statement of an SQL text. Start and End are
+// byte offsets into the original text, excluding the terminating semicolon.
+type sqlStatement struct {
+	Text   string
+	Start  int
+	End    int
+	Tokens []sqlToken
+}
+
+// splitStatements splits query into its statements. Semicolons within
+// strings, quoted identifiers and comments of the dialect are not treated as
+// separators, nor are those within the BEGIN ... END body of a CREATE
+// statement if the dialect has block bodies. Statements that consist only
+// of whitespace and comments are skipped.
+func splitStatements(query string, dialect sqlDialect) []sqlStatement {
+	var (
+		stmts   []sqlStatement
+		current []sqlToken
+		start   int
+		depth   int // nesting of BEGIN ... END blocks and CASE expressions within them
+	)
+
+	addStatement := func(end int) {
+		if hasCode(current) {
+			stmts = append(stmts, sqlStatement{
+				Text:   strings.TrimSpace(query[start:end]),
+				Start:  start,
+				End:    end,
+				Tokens: current,
+			})
+		}
+
+		current = nil
+		depth = 0
+	}
+
+	tokens := tokenizeDialect(query, dialect)
+
+	for idx := 0; idx < len(tokens); idx++ {
+		tok := tokens[idx]
+
+		if tok.Kind == tokenPunct && tok.Text == ";" && depth == 0 {
+			addStatement(tok.Start)
+			start = tok.End
+
+			continue
+		}
+
+		current = append(current, tok)
+
+		if !dialect.BlockBodies || tok.Kind != tokenWord {
+			continue
+		}
+
+		switch strings.ToUpper(tok.Text) {
+		case "BEGIN":
+			if depth > 0 || (sqlStatement{Tokens: current}).keyword() == "CREATE" {
+				depth++
+			}
+		case "CASE":
+			if depth > 0 {
+				depth++
+			}
+		case "END":
+			if depth == 0 {
+				continue
+			}
+
+			// END IF, END LOOP etc. close blocks that aren't counted, while
+			// END CASE closes a CASE statement.
+			next := nextCodeToken(tokens, idx+1)
+			if next < 0 || tokens[next].Kind != tokenWord {
+				depth--
+
+				continue
+			}
+
+			switch strings.ToUpper(tokens[next].Text) {
+			case "IF", "LOOP", "WHILE", "REPEAT":
+			case "CASE":
+				depth--
+
+				current = append(current, tokens[idx+1:next+1]...)
+				idx = next
+			default:
+				depth--
+			}
+		}
+	}
+
+	addStatement(len(query))
+
+	return stmts
+}
+
+// nextCodeToken returns the index of the first token from idx on that is
+// neither whitespace nor a comment, or -1 if there is none.
+func nextCodeToken(tokens []sqlToken, idx int) int {
+	for ; idx < len(tokens); idx++ {
+		if tokens[idx].Kind != tokenWhitespace && tokens[idx].Kind != tokenComment {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func hasCode(tokens []sqlToken) bool {
+	for _, tok := range tokens {
+		if tok.Kind != tokenWhitespace && tok.Kind != tokenComment {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keyword returns the first keyword of the statement in upper case.
+func (s sqlStatement) keyword() string {
+	for _, tok := range s.Tokens {
+		switch tok.Kind {
+		case tokenWhitespace, tokenComment, tokenPunct:
+			continue
+		case tokenWord:
+			return strings.ToUpper(tok.Text)
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func (s sqlStatement) hasKeyword(keyword string) bool {
+	for _, tok := range s.Tokens {
+		if tok.Kind == tokenWord && strings.EqualFold(tok.Text, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mainKeyword returns the keyword of the statement, or for a WITH statement
+// the keyword of the statement following the common table expressions. It
+// returns WITH if that statement can't be found.
+func (s sqlStatement) mainKeyword() string {
+	keyword := s.keyword()
+	if keyword != "WITH" {
+		return keyword
+	}
+
+	var (
+		depth     int
+		started   bool
+		isBody    bool // whether the next parenthesis encloses the body of a common table expression.
+		afterBody bool
+	)
+
+	for _, tok := range s.Tokens {
+		switch {
+		case tok.Kind == tokenWhitespace || tok.Kind == tokenComment:
+			continue
+		case !started: // skip WITH itself.
+			started = true
+		case tok.Kind == tokenPunct && tok.Text == "(":
+			if depth == 0 && isBody {
+				isBody, afterBody = false, true
+			}
+
+			depth++
+		case tok.Kind == tokenPunct && tok.Text == ")":
+			depth--
+		case depth > 0:
+			continue
+		case tok.Kind == tokenPunct && tok.Text == ",":
+			afterBody = false
+		case tok.Kind == tokenWord && strings.EqualFold(tok.Text, "AS"):
+			isBody = true
+		case tok.Kind == tokenWord && afterBody && cteStatementKeywords[strings.ToUpper(tok.Text)]:
+			return strings.ToUpper(tok.Text)
+		}
+	}
+
+	return keyword
+}
+
+// cteStatementKeywords are the keywords of statements that can follow common
+// table expressions.
+var cteStatementKeywords = map[string]bool{
+	"SELECT": true,
+	"VALUES": true,
+	"TABLE":  true,
+	"INSERT": true,
+	"UPDATE": true,
+	"DELETE": true,
+	"MERGE":  true,
+}
+
+var rowReturningKeywords = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"SHOW":     true,
+	"EXPLAIN":  true,
+	"PRAGMA":   true,
+	"VALUES":   true,
+	"TABLE":    true,
+	"DESCRIBE": true,
+	"DESC":     true,
+}
+
+var dmlKeywords = map[string]bool{
+	"INSERT":  true,
+	"UPDATE":  true,
+	"DELETE":  true,
+	"MERGE":   true,
+	"REPLACE": true,
+}
+
+// returnsRows returns whether the statement is expected to return rows.
+func (s sqlStatement) returnsRows() bool {
+	return rowReturningKeywords[s.mainKeyword()] || s.hasKeyword("RETURNING")
+}
+
+// isDML returns whether the statement modifies rows.
+func (s sqlStatement) isDML() bool {
+	return dmlKeywords[s.mainKeyword()]
+}
+
+// statementAt returns the statement of query that contains the byte offset
+// pos. A position right after a statement's last character still belongs to
+// that statement, as does a position between a statement and the code of the
+// next one, e.g. right after a terminating semicolon.
+func statementAt(query string, pos int, dialect sqlDialect) (sqlStatement, bool) {
+	var (
+		found sqlStatement
+		ok    bool
+	)
+
+	for _, stmt := range splitStatements(query, dialect) {
+		if stmt.codeStart() > pos && ok {
+			break
+		}
+
+		found, ok = stmt, true
+
+		if pos <= stmt.End {
+			break
+		}
+	}
+
+	return found, ok
+}
+
+// codeStart returns the offset of the statement's first token that is
+// neither whitespace nor a comment.
+func (s sqlStatement) codeStart() int {
+	for _, tok := range s.Tokens {
+		if tok.Kind != tokenWhitespace && tok.Kind != tokenComment {
+			return tok.Start
+		}
+	}
+
+	return s.End
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+var (
+	sqliteDialect   = supportedDrivers["sqlite"].Dialect
+	postgresDialect = supportedDrivers["postgres"].Dialect
+	mysqlDialect    = supportedDrivers["mysql"].Dialect
+)
+
+func statementTexts(stmts []sqlStatement) []string {
+	var texts []string
+	for _, stmt := range stmts {
+		texts = append(texts, stmt.Text)
+	}
+
+	return texts
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect sqlDialect
+		query   string
+		want    []string
+	}{
+		{
+			name:    "single statement",
+			dialect: genericDialect,
+			query:   "SELECT 1",
+			want:    []string{"SELECT 1"},
+		},
+		{
+			name:    "multiple statements",
+			dialect: genericDialect,
+			query:   "SELECT 1;\n  SELECT 2;\n",
+			want:    []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:    "empty statements and comments are skipped",
+			dialect: genericDialect,
+			query:   ";; -- nothing;\n/* still ; nothing */;SELECT 1",
+			want:    []string{"SELECT 1"},
+		},
+		{
+			name:    "semicolons in strings and identifiers",
+			dialect: genericDialect,
+			query:   `SELECT 'a;b', "c;d", ` + "`e;f`" + `; SELECT 2`,
+			want:    []string{`SELECT 'a;b', "c;d", ` + "`e;f`", "SELECT 2"},
+		},
+		{
+			name:    "doubled quotes",
+			dialect: genericDialect,
+			query:   "SELECT 'it''s; here'; SELECT 2",
+			want:    []string{"SELECT 'it''s; here'", "SELECT 2"},
+		},
+		{
+			name:    "postgres dollar quotes",
+			dialect: postgresDialect,
+			query:   "CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql; SELECT $$;$$",
+			want:    []string{"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql", "SELECT $$;$$"},
+		},
+		{
+			name:    "mysql backslash escapes",
+			dialect: mysqlDialect,
+			query:   `SELECT 'it\'s; x'; SELECT "a;b"`,
+			want:    []string{`SELECT 'it\'s; x'`, `SELECT "a;b"`},
+		},
+		{
+			name:    "mysql hash comments",
+			dialect: mysqlDialect,
+			query:   "SELECT 1 # comment; still comment\n; SELECT 2",
+			want:    []string{"SELECT 1 # comment; still comment", "SELECT 2"},
+		},
+		{
+			name:    "hash is no comment in sqlite",
+			dialect: sqliteDialect,
+			query:   "SELECT 1 # 2; SELECT 3",
+			want:    []string{"SELECT 1 # 2", "SELECT 3"},
+		},
+		{
+			name:    "sqlite trigger",
+			dialect: sqliteDialect,
+			query: "CREATE TRIGGER t AFTER INSERT ON a BEGIN\n" +
+				"  UPDATE b SET n = CASE WHEN new.x > 0 THEN 1 ELSE 0 END;\n" +
+				"  DELETE FROM c;\n" +
+				"END;\n" +
+				"SELECT 1",
+			want: []string{
+				"CREATE TRIGGER t AFTER INSERT ON a BEGIN\n" +
+					"  UPDATE b SET n = CASE WHEN new.x > 0 THEN 1 ELSE 0 END;\n" +
+					"  DELETE FROM c;\n" +
+					"END",
+				"SELECT 1",
+			},
+		},
+		{
+			name:    "transaction statements are no blocks",
+			dialect: sqliteDialect,
+			query:   "BEGIN; UPDATE a SET x = 1; END; SELECT 1",
+			want:    []string{"BEGIN", "UPDATE a SET x = 1", "END", "SELECT 1"},
+		},
+		{
+			name:    "mysql procedure",
+			dialect: mysqlDialect,
+			query: "CREATE PROCEDURE p() BEGIN\n" +
+				"  IF x THEN SELECT 1; END IF;\n" +
+				"  CASE y WHEN 1 THEN SELECT 2; END CASE;\n" +
+				"  SELECT 3;\n" +
+				"END; SELECT 4",
+			want: []string{
+				"CREATE PROCEDURE p() BEGIN\n" +
+					"  IF x THEN SELECT 1; END IF;\n" +
+					"  CASE y WHEN 1 THEN SELECT 2; END CASE;\n" +
+					"  SELECT 3;\n" +
+					"END",
+				"SELECT 4",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := statementTexts(splitStatements(tt.query, tt.dialect))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatementAt(t *testing.T) {
+	const query = "SELECT 1;\n\nSELECT 'a;b';  -- c\nSELECT 3"
+
+	tests := []struct {
+		pos    int
+		want   string
+		wantOK bool
+	}{
+		{pos: 0, want: "SELECT 1", wantOK: true},
+		{pos: 8, want: "SELECT 1", wantOK: true},
+		{pos: 9, want: "SELECT 1", wantOK: true},
+		{pos: 10, want: "SELECT 1", wantOK: true},
+		{pos: 11, want: "SELECT 'a;b'", wantOK: true},
+		{pos: 12, want: "SELECT 'a;b'", wantOK: true},
+		{pos: 20, want: "SELECT 'a;b'", wantOK: true},
+		{pos: len(query), want: "-- c\nSELECT 3", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := statementAt(query, tt.pos, genericDialect)
+		if ok != tt.wantOK || got.Text != tt.want {
+			t.Errorf("statementAt(%d) = %q, %t, want %q, %t", tt.pos, got.Text, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	if _, ok := statementAt("  -- nothing", 0, genericDialect); ok {
+		t.Errorf("statementAt found a statement in a comment")
+	}
+}
+
+func TestStatementKind(t *testing.T) {
+	tests := []struct {
+		query       string
+		returnsRows bool
+		isDML       bool
+	}{
+		{query: "select * from t", returnsRows: true},
+		{query: "/* x */ WITH a AS (SELECT 1) SELECT * FROM a", returnsRows: true},
+		{query: "WITH RECURSIVE a(n) AS (SELECT 1 UNION SELECT n + 1 FROM a WHERE n < 3), b AS NOT MATERIALIZED (SELECT 2) VALUES (1)", returnsRows: true},
+		{query: "WITH a AS (SELECT id FROM t) UPDATE t SET x = 1 WHERE id IN (SELECT id FROM a)", isDML: true},
+		{query: "with a as (select 1) delete from t", isDML: true},
+		{query: "WITH a AS (SELECT 1) INSERT INTO t SELECT * FROM a RETURNING id", returnsRows: true, isDML: true},
+		{query: "WITH a AS (SELECT 1)", returnsRows: true},
+		{query: "insert into t values (1)", isDML: true},
+		{query: "DELETE FROM t RETURNING id", returnsRows: true, isDML: true},
+		{query: "CREATE TABLE t (id int)"},
+	}
+
+	for _, tt := range tests {
+		stmts := splitStatements(tt.query, genericDialect)
+		if len(stmts) != 1 {
+			t.Fatalf("splitStatements(%q) returned %d statements", tt.query, len(stmts))
+		}
+
+		if got := stmts[0].returnsRows(); got != tt.returnsRows {
+			t.Errorf("%q: returnsRows() = %t, want %t", tt.query, got, tt.returnsRows)
+		}
+
+		if got := stmts[0].isDML(); got != tt.isDML {
+			t.Errorf("%q: isDML() = %t, want %t", tt.query, got, tt.isDML)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// qualifiedName returns the quoted name of a table, qualified with its
+// schema unless schema is empty.
+func qualifiedName(driver, schema, table string) string {
+	name := quoteIdentifier(driver, table)
+	if schema != "" {
+		name = quoteIdentifier(driver, schema) + "." + name
+	}
+
+	return name
+}
+
+// previewQuery returns a query for the first limit rows of a table.
+func previewQuery(driver, schema, table string, limit int) string {
+	return fmt.Sprintf("SELECT * FROM %s LIMIT %d", qualifiedName(driver, schema, table), limit)
+}
+
+type queryTemplate int
+
+const (
+	templateSelect queryTemplate = iota
+	templateInsert
+	templateUpdate
+)
+
+var queryTemplateNames = []string{"SELECT", "INSERT", "UPDATE"}
+
+// generateTemplate returns a statement of the given type for a table that
+// lists all of its columns. Values are NULL and the condition of an UPDATE
+// matches no rows, so that they have to be filled in first.
+func generateTemplate(tmpl queryTemplate, driver, schema, table string, columns []column) string {
+	name := qualifiedName(driver, schema, table)
+
+	quoted := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quoted = append(quoted, quoteIdentifier(driver, col.Name))
+	}
+
+	switch tmpl {
+	case templateInsert:
+		values := make([]string, len(quoted))
+		for idx := range values {
+			values[idx] = "NULL"
+		}
+
+		return "INSERT INTO " + name + " (" + strings.Join(quoted, ", ") + ")\nVALUES (" + strings.Join(values, ", ") + ");\n"
+	case templateUpdate:
+		assignments := make([]string, 0, len(quoted))
+		for _, col := range quoted {
+			assignments = append(assignments, col+" = NULL")
+		}
+
+		return "UPDATE " + name + "\nSET " + strings.Join(assignments, ",\n\t") + "\nWHERE 1 = 0;\n"
+	default:
+		return "SELECT\n\t" + strings.Join(quoted, ",\n\t") + "\nFROM " + name + ";\n"
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// normalizeValue converts a value as returned by a database driver into a
+// value that is independent of the driver's representation: text that is
+// returned as []byte becomes a string, and numbers that are returned as
+// text (as MySQL does) become a json.Number. Binary data stays []byte.
+// Whether []byte is binary data or text is decided by the database type
+// name dbType, and only guessed from the data if the type is unknown.
+func normalizeValue(v interface{}, dbType string) interface{} {
+	var text string
+
+	switch value := v.(type) {
+	case []byte:
+		switch {
+		case isBinaryType(dbType):
+			return value
+		case !isTextType(dbType) && !utf8.Valid(value):
+			return value
+		}
+
+		text = string(value)
+	case string:
+		text = value
+	default:
+		return v
+	}
+
+	if isNumericType(dbType) && isNumber(text) {
+		return json.Number(text)
+	}
+
+	return text
+}
+
+var (
+	binaryTypes = []string{"BLOB", "BYTEA", "BINARY", "GEOMETRY"}
+	textTypes   = []string{"CHAR", "TEXT", "CLOB", "STRING", "JSON", "XML", "ENUM", "UUID", "DATE", "TIME"}
+)
+
+// isBinaryType returns whether the database type name dbType denotes a
+// binary type, e.g. BLOB, BYTEA or VARBINARY.
+func isBinaryType(dbType string) bool {
+	return containsAny(strings.ToUpper(dbType), binaryTypes)
+}
+
+// isTextType returns whether the database type name dbType denotes a type
+// whose values are text, e.g. VARCHAR, TEXT, JSON or a number.
+func isTextType(dbType string) bool {
+	return containsAny(strings.ToUpper(dbType), textTypes) || isNumericType(dbType)
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var numericTypes = []string{"INT", "DEC", "NUMERIC", "REAL", "FLOAT", "DOUBLE"}
+
+// isNumericType returns whether the database type name dbType denotes a
+// numeric type, e.g. INTEGER, BIGINT, DECIMAL or DOUBLE PRECISION.
+func isNumericType(dbType string) bool {
+	dbType = strings.ToUpper(dbType)
+
+	if strings.Contains(dbType, "INTERVAL") || strings.Contains(dbType, "POINT") {
+		return false
+	}
+
+	return containsAny(dbType, numericTypes)
+}
+
+// isNumber returns whether s is a valid JSON number.
+func isNumber(s string) bool {
+	return s != "" && (s[0] == '-' || isDigit(s[0])) && json.Valid([]byte(s))
+}
+
+const timeLayout = "2006-01-02 15:04:05.999999999Z07:00"
+
+// valueString converts a normalized value to its plain text representation.
+// NULL values are converted to null.
+func valueString(v interface{}, null string) string {
+	switch value := v.(type) {
+	case nil:
+		return null
+	case string:
+		return value
+	case []byte:
+		return fmt.Sprintf("\\x%x", value)
+	case time.Time:
+		return value.Format(timeLayout)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// parseCellValue converts text that was entered for a cell to the type of
+// the cell's previous value if possible. Everything else is kept as text.
+func parseCellValue(text string, previous interface{}) interface{} {
+	switch previous.(type) {
+	case int64:
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i
+		}
+	case float64:
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	case json.Number:
+		if isNumber(text) {
+			return json.Number(text)
+		}
+	}
+
+	return text
+}
+
+// prettyText indents text that is JSON or XML, which is detected by the
+// database type name dbType or by the text itself. Other text, and text
+// that can't be parsed, is returned unchanged.
+func prettyText(text, dbType string) string {
+	dbType = strings.ToUpper(dbType)
+	trimmed := strings.TrimSpace(text)
+
+	switch {
+	case strings.Contains(dbType, "JSON") || strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "["):
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, []byte(trimmed), "", "  "); err == nil {
+			return buf.String()
+		}
+	case strings.Contains(dbType, "XML") || strings.HasPrefix(trimmed, "<"):
+		if pretty, err := indentXML(trimmed); err == nil {
+			return pretty
+		}
+	}
+
+	return text
+}
+
+// indentXML re-encodes an XML document with indentation.
+func indentXML(text string) (string, error) {
+	var buf bytes.Buffer
+
+	dec := xml.NewDecoder(strings.NewReader(text))
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		// whitespace between elements is replaced by the indentation.
+		if data, ok := tok.(xml.CharData); ok && len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		if err := enc.EncodeToken(xml.CopyToken(tok)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := enc.Flush(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		dbType string
+		want   interface{}
+	}{
+		{name: "nil", value: nil, dbType: "TEXT", want: nil},
+		{name: "text", value: []byte("abc"), dbType: "VARCHAR", want: "abc"},
+		{name: "text with invalid bytes", value: []byte{0xff, 'a'}, dbType: "TEXT", want: string([]byte{0xff, 'a'})},
+		{name: "blob that is valid UTF-8", value: []byte{0x00, 0x01}, dbType: "BLOB", want: []byte{0x00, 0x01}},
+		{name: "bytea", value: []byte("abc"), dbType: "BYTEA", want: []byte("abc")},
+		{name: "varbinary", value: []byte("abc"), dbType: "VARBINARY", want: []byte("abc")},
+		{name: "unknown type with text", value: []byte("abc"), dbType: "", want: "abc"},
+		{name: "unknown type with binary data", value: []byte{0xff, 0xfe}, dbType: "", want: []byte{0xff, 0xfe}},
+		{name: "decimal", value: []byte("12.50"), dbType: "DECIMAL", want: json.Number("12.50")},
+		{name: "number in text column", value: []byte("12"), dbType: "TEXT", want: "12"},
+		{name: "int64", value: int64(3), dbType: "INTEGER", want: int64(3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeValue(tt.value, tt.dbType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeValue(%#v, %q) = %#v, want %#v", tt.value, tt.dbType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueString(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: nil, want: "NULL"},
+		{value: "abc", want: "abc"},
+		{value: []byte{0x00, 0xab}, want: `\x00ab`},
+		{value: json.Number("1.50"), want: "1.50"},
+		{value: int64(-2), want: "-2"},
+	}
+
+	for _, tt := range tests {
+		if got := valueString(tt.value, "NULL"); got != tt.want {
+			t.Errorf("valueString(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
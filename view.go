@@ -1,13 +1,15 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"os"
 	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/navidys/tvxwidgets"
@@ -20,7 +22,7 @@ type mainView struct {
 	app          *tview.Application
 	layout       *tview.Flex
 	dbTree       *tview.TreeView
-	queryInput   *tview.TextArea
+	queryInput   *queryEditor
 	resultTable  *tview.Table
 	infoLine     *tview.Flex
 	contextField *tview.TextView
@@ -31,14 +33,38 @@ type mainView struct {
 
 	dbRootNode *tview.TreeNode
 
-	currentResultTableRow int
-	currentDB             string // currently selected dbID
+	snippetTree     *tview.TreeView
+	snippetRootNode *tview.TreeNode
+
+	pageSize  int        // number of rows fetched at once when scrolling through result
+	format    cellFormat // how values are rendered in result table
+	currentDB string     // currently selected dbID
+	clipboard string     // text copied in the query input or DDL viewer
+
+	clipboardCfg clipboardConfig
+	copyScope    copyScope // what was copied from the result last time
+	copyFormat   int       // index into copyFormats of the format used last time
 
 	keyMapping       map[string]string    // mapping of key to operation name
 	operationMapping map[string]operation // mapping of operation name to operation
 
 	queryTabs   []string
 	queryTabIdx int
+	tabs        []*queryTab // state of the query tabs, may include one more tab than queryTabs
+	runningTab  *queryTab   // tab of the currently running query
+
+	queryMtx    sync.Mutex
+	cancelQuery context.CancelFunc // cancels the currently running query, nil if no query is running
+}
+
+// queryTab is the state of a query tab besides its query.
+type queryTab struct {
+	name      string
+	dbID      string                // database that the tab is bound to, empty if none
+	params    map[string]string     // last parameter values of the query
+	numbers   bool                  // whether numbers among the parameter values were passed as numbers
+	results   []*resultTableContent // results of the statements of the query last executed in the tab
+	resultIdx int                   // index of result currently shown in result table
 }
 
 type operation struct {
@@ -47,9 +73,12 @@ type operation struct {
 }
 
 type nodeRef struct {
-	Type  nodeType
-	DB    string
-	Table string
+	Type   nodeType
+	DB     string
+	Schema string // empty if the driver doesn't support browsing schemas
+	Table  string
+	Name   string     // name of an object other than a table
+	Kind   objectKind // kind of the objects in a folder, or of a table
 }
 
 type nodeType int
@@ -57,6 +86,9 @@ type nodeType int
 const (
 	typeDB nodeType = iota
 	typeTable
+	typeSchema
+	typeFolder // objects of one kind in a schema or of a table
+	typeObject
 )
 
 var (
@@ -70,6 +102,8 @@ func newMainView() *mainView {
 		operationMapping: make(map[string]operation),
 		queryTabs:        []string{""},
 		queryTabIdx:      0,
+		pageSize:         defaultPageSize,
+		format:           defaultCellFormat,
 	}
 	view.setup()
 
@@ -105,6 +139,38 @@ func (v *mainView) configure(cfg config) error {
 		Function:    v.execQuery,
 		Description: "Execute query in input field and show result in table below",
 	}
+	v.operationMapping["exec-statement"] = operation{
+		Function:    v.execStatement,
+		Description: "Execute selected text or statement at cursor",
+	}
+	v.operationMapping["cancel-query"] = operation{
+		Function:    v.cancelRunningQuery,
+		Description: "Cancel currently running query",
+	}
+	v.operationMapping["next-result"] = operation{
+		Function:    v.nextResult,
+		Description: "Show result of next statement",
+	}
+	v.operationMapping["prev-result"] = operation{
+		Function:    v.prevResult,
+		Description: "Show result of previous statement",
+	}
+	v.operationMapping["begin-tx"] = operation{
+		Function:    v.beginTx,
+		Description: "Begin transaction on current database",
+	}
+	v.operationMapping["commit-tx"] = operation{
+		Function:    v.commitTx,
+		Description: "Commit transaction on current database",
+	}
+	v.operationMapping["rollback-tx"] = operation{
+		Function:    v.rollbackTx,
+		Description: "Roll back transaction on current database",
+	}
+	v.operationMapping["show-history"] = operation{
+		Function:    v.showHistory,
+		Description: "Show query history",
+	}
 	v.operationMapping["show-help"] = operation{
 		Function:    v.showHelp,
 		Description: "Show help screen",
@@ -117,6 +183,10 @@ func (v *mainView) configure(cfg config) error {
 		Function:    v.prevQueryTab,
 		Description: "Go to previous query tab",
 	}
+	v.operationMapping["rename-tab"] = operation{
+		Function:    v.renameTab,
+		Description: "Rename current query tab",
+	}
 	v.operationMapping["close-tab"] = operation{
 		Function:    v.closeQueryTab,
 		Description: "Close current query tab",
@@ -127,13 +197,96 @@ func (v *mainView) configure(cfg config) error {
 	}
 	v.operationMapping["download-result"] = operation{
 		Function:    v.downloadResult,
-		Description: "Download result to CSV file",
+		Description: "Export result to file",
+	}
+
+	v.operationMapping["explain-query"] = operation{
+		Function:    v.explainQuery,
+		Description: "Show query plan of selected text or statement at cursor",
+	}
+	v.operationMapping["show-ddl"] = operation{
+		Function:    v.showDDL,
+		Description: "Show DDL of table, view or index selected in database tree",
+	}
+	v.operationMapping["preview-table"] = operation{
+		Function:    v.previewTable,
+		Description: "Show first rows of table or view selected in database tree",
+	}
+	v.operationMapping["generate-template"] = operation{
+		Function:    v.generateTemplate,
+		Description: "Open SELECT, INSERT or UPDATE template for table selected in database tree",
+	}
+	v.operationMapping["insert-row"] = operation{
+		Function:    v.insertRow,
+		Description: "Add row to result for inserting it into its table",
+	}
+	v.operationMapping["delete-row"] = operation{
+		Function:    v.deleteRow,
+		Description: "Mark selected row of result for deletion, or unmark it",
+	}
+	v.operationMapping["apply-changes"] = operation{
+		Function:    v.applyChanges,
+		Description: "Preview and apply pending changes of result to database",
+	}
+	v.operationMapping["discard-changes"] = operation{
+		Function:    v.discardChanges,
+		Description: "Discard pending changes of result",
+	}
+	v.operationMapping["search-result"] = operation{
+		Function:    v.searchResult,
+		Description: "Search loaded rows of result and highlight matches",
+	}
+	v.operationMapping["next-match"] = operation{
+		Function:    v.nextMatch,
+		Description: "Go to next search match in result",
+	}
+	v.operationMapping["prev-match"] = operation{
+		Function:    v.prevMatch,
+		Description: "Go to previous search match in result",
+	}
+	v.operationMapping["sort-result"] = operation{
+		Function:    v.sortResult,
+		Description: "Sort loaded rows of result by selected column (ascending, descending, original order)",
+	}
+	v.operationMapping["filter-result"] = operation{
+		Function:    v.filterResult,
+		Description: "Show only loaded rows of result that match a filter expression",
+	}
+	v.operationMapping["show-row"] = operation{
+		Function:    v.showRow,
+		Description: "Show selected row of result as list of columns and values",
+	}
+	v.operationMapping["mark-cells"] = operation{
+		Function:    v.markCells,
+		Description: "Start or end selection of cells in result at selected cell",
+	}
+	v.operationMapping["copy-result"] = operation{
+		Function:    v.copyResult,
+		Description: "Copy cell, row, column, selection or all rows of result to clipboard",
+	}
+	v.operationMapping["goto-snippets"] = operation{
+		Function:    v.gotoSnippets,
+		Description: "Go to snippet library and reload it",
+	}
+	v.operationMapping["save-snippet"] = operation{
+		Function:    v.saveSnippet,
+		Description: "Save query of current tab to snippet library",
+	}
+	v.operationMapping["complete"] = operation{
+		Function:    v.showCompletions,
+		Description: "Complete keyword, table or column at cursor",
+	}
+	v.operationMapping["refresh-schema"] = operation{
+		Function:    v.refreshSchema,
+		Description: "Reload tables and columns of current database for completion",
 	}
 
 	v.keyMapping["Ctrl+A"] = "add-db"
 	v.keyMapping["Ctrl+D"] = "download-result"
+	v.keyMapping["Ctrl+G"] = "cancel-query"
 	v.keyMapping["Tab"] = "goto-queryinput" // Ctrl+I
 	v.keyMapping["Ctrl+N"] = "next-query-tab"
+	v.keyMapping["Ctrl+O"] = "complete"
 	v.keyMapping["Ctrl+Q"] = "quit"
 	v.keyMapping["Ctrl+P"] = "prev-query-tab"
 	v.keyMapping["Ctrl+R"] = "goto-result"
@@ -143,8 +296,52 @@ func (v *mainView) configure(cfg config) error {
 	v.keyMapping["Ctrl+Y"] = "close-db"
 
 	v.keyMapping["Ctrl+Space"] = "exec-query"
+	v.keyMapping["Alt+Enter"] = "exec-statement"
+	v.keyMapping["Alt+Rune[n]"] = "next-result"
+	v.keyMapping["Alt+Rune[p]"] = "prev-result"
+	v.keyMapping["Alt+Rune[b]"] = "begin-tx"
+	v.keyMapping["Alt+Rune[c]"] = "commit-tx"
+	v.keyMapping["Alt+Rune[r]"] = "rollback-tx"
+	v.keyMapping["Alt+Rune[h]"] = "show-history"
+	v.keyMapping["Alt+Rune[s]"] = "refresh-schema"
+	v.keyMapping["Alt+Rune[e]"] = "explain-query"
+	v.keyMapping["Alt+Rune[d]"] = "show-ddl"
+	v.keyMapping["Alt+Rune[v]"] = "preview-table"
+	v.keyMapping["Alt+Rune[g]"] = "generate-template"
+	v.keyMapping["Alt+Rune[i]"] = "insert-row"
+	v.keyMapping["Alt+Rune[x]"] = "delete-row"
+	v.keyMapping["Alt+Rune[w]"] = "apply-changes"
+	v.keyMapping["Alt+Rune[u]"] = "discard-changes"
+	v.keyMapping["Alt+Rune[f]"] = "search-result"
+	v.keyMapping["Alt+Rune[.]"] = "next-match"
+	v.keyMapping["Alt+Rune[,]"] = "prev-match"
+	v.keyMapping["Alt+Rune[o]"] = "sort-result"
+	v.keyMapping["Alt+Rune[l]"] = "filter-result"
+	v.keyMapping["Alt+Rune[k]"] = "show-row"
+	v.keyMapping["Alt+Rune[m]"] = "mark-cells"
+	v.keyMapping["Alt+Rune[y]"] = "copy-result"
+	v.keyMapping["Alt+Rune[t]"] = "goto-snippets"
+	v.keyMapping["Alt+Rune[a]"] = "save-snippet"
+	v.keyMapping["Alt+Rune[q]"] = "rename-tab"
 	v.keyMapping["Rune[?]"] = "show-help"
 
+	if cfg.PageSize > 0 {
+		v.pageSize = cfg.PageSize
+	}
+
+	if cfg.TimeFormat != "" {
+		v.format.TimeLayout = cfg.TimeFormat
+	}
+
+	v.clipboardCfg = cfg.Clipboard
+
+	colors, err := cfg.SyntaxColors.syntaxColors()
+	if err != nil {
+		return fmt.Errorf("invalid syntax colors: %w", err)
+	}
+
+	v.queryInput.setColors(colors)
+
 	for _, keyCfg := range cfg.Keys {
 		v.keyMapping[keyCfg.Key] = keyCfg.Operation
 	}
@@ -172,13 +369,35 @@ func (v *mainView) setup() {
 	v.dbTree.SetRoot(v.dbRootNode).SetCurrentNode(v.dbRootNode)
 	v.dbTree.SetSelectedFunc(v.treeNodeSelected)
 
-	v.queryInput = tview.NewTextArea()
+	v.snippetRootNode = tview.NewTreeNode("Snippets")
+
+	v.snippetTree = tview.NewTreeView()
+	v.snippetTree.SetBorder(true).SetTitle("Snippets")
+	v.snippetTree.SetRoot(v.snippetRootNode).SetCurrentNode(v.snippetRootNode)
+	v.snippetTree.SetSelectedFunc(v.snippetNodeSelected)
+	v.snippetTree.SetChangedFunc(func(node *tview.TreeNode) {
+		if s, ok := node.GetReference().(*snippet); ok {
+			v.activityPlaceholder.SetText(s.Description)
+		}
+	})
+
+	v.queryInput = newQueryEditor()
 	v.queryInput.SetBorder(true)
+	v.queryInput.SetClipboard(v.copyToClipboard, v.pasteFromClipboard)
 	v.updateQueryInputTitle()
 
 	v.resultTable = tview.NewTable()
 	v.resultTable.SetBorder(true).SetTitle("Result")
 	v.resultTable.SetBorders(true)
+	v.resultTable.SetSelectable(true, true).SetFixed(1, 0)
+	v.resultTable.SetSelectedFunc(func(row, column int) {
+		v.editCell()
+	})
+	v.resultTable.SetSelectionChangedFunc(func(row, column int) {
+		if result := v.currentResult(); result != nil {
+			result.moveCursor(row, column)
+		}
+	})
 
 	v.contextField = tview.NewTextView()
 	v.activityGauge = tvxwidgets.NewActivityModeGauge()
@@ -191,7 +410,9 @@ func (v *mainView) setup() {
 
 	v.layout = tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(tview.NewFlex().
-			AddItem(v.dbTree, 0, 1, true).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(v.dbTree, 0, 2, true).
+				AddItem(v.snippetTree, 0, 1, false), 0, 1, true).
 			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
 				AddItem(v.queryInput, 0, 1, false).
 				AddItem(v.resultTable, 0, 3, false), 0, 3, false), 0, 1, false).
@@ -220,15 +441,46 @@ func (v *mainView) restoreSession(queries *queriesData) {
 		v.queryInput.SetText(v.queryTabs[v.queryTabIdx], true)
 	}
 
-	v.updateQueryInputTitle()
+	v.tabs = make([]*queryTab, len(v.queryTabs))
+	for idx := range v.tabs {
+		tab := &queryTab{}
+
+		if idx < len(queries.Names) {
+			tab.name = queries.Names[idx]
+		}
+
+		if idx < len(queries.Params) {
+			tab.params = queries.Params[idx]
+		}
+
+		if idx < len(queries.DBs) {
+			tab.dbID, _ = v.findOpenDB(func(dbID string) bool {
+				return v.tabDB(dbID) == queries.DBs[idx]
+			})
+		}
+
+		v.tabs[idx] = tab
+	}
+
+	v.enterTab()
 }
 
 func (v *mainView) getSession() *queriesData {
 	if len(v.queryTabs) > 1 || v.queryTabs[0] != "" {
-		return &queriesData{
+		queries := &queriesData{
 			Tabs:  v.queryTabs,
 			Index: v.queryTabIdx,
 		}
+
+		for idx := range v.queryTabs {
+			tab := v.tabAt(idx)
+
+			queries.Names = append(queries.Names, tab.name)
+			queries.Params = append(queries.Params, tab.params)
+			queries.DBs = append(queries.DBs, v.tabDB(tab.dbID))
+		}
+
+		return queries
 	}
 
 	return nil
@@ -240,13 +492,91 @@ func (v *mainView) updateQueryInputTitle() {
 		total++
 	}
 
-	v.queryInput.SetTitle(fmt.Sprintf("Query %d/%d", v.queryTabIdx+1, total))
+	title := fmt.Sprintf("Query %d/%d", v.queryTabIdx+1, total)
+
+	tab := v.tab()
+	if tab.name != "" {
+		title += ": " + tab.name
+	}
+
+	if tab.dbID != "" {
+		title += " @ " + v.ctrl.getDatabaseName(tab.dbID)
+	}
+
+	v.queryInput.SetTitle(title)
+}
+
+// tabDB returns the identity of an open database for storing the binding
+// of a query tab in the session.
+func (v *mainView) tabDB(dbID string) queryTabDB {
+	if dbID == "" {
+		return queryTabDB{}
+	}
+
+	if profile := v.ctrl.getProfileName(dbID); profile != "" {
+		return queryTabDB{Profile: profile}
+	}
+
+	return queryTabDB{Connection: v.ctrl.getConnectionKey(dbID)}
+}
+
+// tab returns the state of the current query tab.
+func (v *mainView) tab() *queryTab {
+	return v.tabAt(v.queryTabIdx)
+}
+
+// tabAt returns the state of a query tab, creating it if necessary.
+func (v *mainView) tabAt(idx int) *queryTab {
+	for len(v.tabs) <= idx {
+		v.tabs = append(v.tabs, &queryTab{})
+	}
+
+	return v.tabs[idx]
+}
+
+// enterTab shows the current query tab with its database and its results.
+// A tab that isn't bound to a database yet is bound to the current
+// database.
+func (v *mainView) enterTab() {
+	tab := v.tab()
+
+	if tab.dbID != "" {
+		v.setCurrentDB(tab.dbID)
+	} else {
+		tab.dbID = v.currentDB
+	}
+
+	v.showResult(tab.resultIdx)
+	v.updateQueryInputTitle()
+}
+
+// renameTab asks for a new name of the current query tab.
+func (v *mainView) renameTab() {
+	form := tview.NewForm()
+	form.AddInputField("Name", v.tab().name, 40, nil, nil)
+	form.AddButton("Rename", func() {
+		v.tab().name = strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		v.showMainView()
+		v.updateQueryInputTitle()
+		v.app.SetFocus(v.queryInput)
+	}).AddButton("Cancel", func() {
+		v.showMainView()
+		v.app.SetFocus(v.queryInput)
+	})
+	form.SetCancelFunc(func() {
+		v.showMainView()
+		v.app.SetFocus(v.queryInput)
+	})
+	form.SetBorder(true).SetTitle("Rename Query Tab")
+	v.app.SetRoot(form, true)
 }
 
 func (v *mainView) startActivityGauge() {
 	log.Printf("Starting activity gauge")
 
 	v.activityGauge.Reset()
+	v.activityGauge.SetPgBgColor(tcell.ColorBlue)
+	v.activityPlaceholder.SetText("Press ? for help")
 	v.infoLine.RemoveItem(v.activityPlaceholder)
 	v.infoLine.AddItem(v.activityGauge, 0, 3, false)
 
@@ -302,43 +632,174 @@ func (v *mainView) treeNodeSelected(node *tview.TreeNode) {
 
 	switch ref.Type {
 	case typeDB:
-		go func() {
-			v.startActivityGauge()
-			defer v.stopActivityGauge()
+		v.loadTreeNodes(node, "schemas", func() ([]*tview.TreeNode, error) {
+			log.Printf("Getting list of schemas from database %s", ref.DB)
 
-			log.Printf("Getting list of tables from database %s", ref.DB)
+			schemas, err := v.ctrl.getSchemas(ref.DB)
+			if errors.Is(err, errBrowseUnsupported) {
+				return v.tableNodes(ref.DB)
+			}
 
-			tables, err := v.ctrl.getTables(ref.DB)
 			if err != nil {
-				v.showError("Listing tables failed: %v", err)
+				return nil, err
+			}
 
-				return
+			// a database with a single schema, like most SQLite databases,
+			// directly shows the objects of the schema.
+			if len(schemas) == 1 {
+				return v.schemaFolderNodes(ref.DB, schemas[0])
 			}
 
-			for _, table := range tables {
-				tblNode := tview.NewTreeNode(table).
+			var nodes []*tview.TreeNode
+
+			for _, schema := range schemas {
+				nodes = append(nodes, tview.NewTreeNode(schema).
 					SetSelectable(true).
-					SetReference(&nodeRef{Type: typeTable, DB: ref.DB, Table: table})
-				node.AddChild(tblNode)
+					SetReference(&nodeRef{Type: typeSchema, DB: ref.DB, Schema: schema}))
 			}
 
-			log.Printf("Finished getting list of tables from database %s", ref.DB)
-		}()
-	case typeTable:
-		go func() {
-			v.startActivityGauge()
-			defer v.stopActivityGauge()
+			return nodes, nil
+		})
+	case typeSchema:
+		v.loadTreeNodes(node, "objects of schema "+ref.Schema, func() ([]*tview.TreeNode, error) {
+			return v.schemaFolderNodes(ref.DB, ref.Schema)
+		})
+	case typeFolder:
+		v.loadTreeNodes(node, strings.ToLower(ref.Kind.String()), func() ([]*tview.TreeNode, error) {
+			var (
+				objects []schemaObject
+				err     error
+			)
+
+			if ref.Table != "" {
+				objects, err = v.ctrl.getTableObjects(ref.DB, ref.Schema, ref.Table, ref.Kind)
+			} else {
+				objects, err = v.ctrl.getSchemaObjects(ref.DB, ref.Schema, ref.Kind)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			var nodes []*tview.TreeNode
+
+			for _, obj := range objects {
+				if ref.Table == "" && ref.Kind.hasColumns() {
+					nodes = append(nodes, tview.NewTreeNode(obj.Name).
+						SetSelectable(true).
+						SetReference(&nodeRef{Type: typeTable, DB: ref.DB, Schema: ref.Schema, Table: obj.Name, Kind: ref.Kind}))
+
+					continue
+				}
+
+				text := obj.Name
+				if obj.Detail != "" {
+					text += " (" + obj.Detail + ")"
+				}
+
+				nodes = append(nodes, tview.NewTreeNode(text).
+					SetSelectable(true).
+					SetReference(&nodeRef{Type: typeObject, DB: ref.DB, Schema: ref.Schema, Table: ref.Table, Name: obj.Name, Kind: ref.Kind}))
+			}
 
-			fields, err := v.ctrl.getTableColumns(ref.DB, ref.Table)
+			return nodes, nil
+		})
+	case typeTable:
+		v.loadTreeNodes(node, "columns for "+ref.Table, func() ([]*tview.TreeNode, error) {
+			fields, err := v.tableColumns(ref)
 			if err != nil {
-				v.showError("Listing columns for %s failed: %v", ref.Table, err)
+				return nil, err
 			}
 
+			var nodes []*tview.TreeNode
+
 			for _, field := range fields {
-				node.AddChild(tview.NewTreeNode(field.Name + " (" + field.Type + ")"))
+				nodes = append(nodes, tview.NewTreeNode(field.Name+" ("+field.Type+")"))
 			}
-		}()
+
+			if ref.Schema != "" && ref.Kind == objectTables {
+				for _, kind := range tableObjectKinds {
+					nodes = append(nodes, tview.NewTreeNode(kind.String()).
+						SetSelectable(true).
+						SetReference(&nodeRef{Type: typeFolder, DB: ref.DB, Schema: ref.Schema, Table: ref.Table, Kind: kind}))
+				}
+			}
+
+			return nodes, nil
+		})
+	}
+}
+
+// loadTreeNodes loads the children of node in the background. what
+// describes the children in error messages.
+func (v *mainView) loadTreeNodes(node *tview.TreeNode, what string, load func() ([]*tview.TreeNode, error)) {
+	go func() {
+		v.startActivityGauge()
+		defer v.stopActivityGauge()
+
+		nodes, err := load()
+
+		v.app.QueueUpdateDraw(func() {
+			if err != nil {
+				v.showError("Listing %s failed: %v", what, err)
+
+				return
+			}
+
+			for _, child := range nodes {
+				node.AddChild(child)
+			}
+
+			node.SetExpanded(true)
+		})
+	}()
+}
+
+// tableColumns returns the columns of the table of a tree node.
+func (v *mainView) tableColumns(ref *nodeRef) ([]column, error) {
+	if ref.Schema != "" {
+		return v.ctrl.getSchemaColumns(ref.DB, ref.Schema, ref.Table)
+	}
+
+	return v.ctrl.getTableColumns(ref.DB, ref.Table)
+}
+
+// tableNodes returns the nodes of the tables of a database whose driver
+// doesn't support browsing schemas.
+func (v *mainView) tableNodes(dbID string) ([]*tview.TreeNode, error) {
+	tables, err := v.ctrl.getTables(dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []*tview.TreeNode
+
+	for _, table := range tables {
+		nodes = append(nodes, tview.NewTreeNode(table).
+			SetSelectable(true).
+			SetReference(&nodeRef{Type: typeTable, DB: dbID, Table: table}))
+	}
+
+	return nodes, nil
+}
+
+// schemaFolderNodes returns a folder node for every kind of object that the
+// driver can list in a schema.
+func (v *mainView) schemaFolderNodes(dbID, schema string) ([]*tview.TreeNode, error) {
+	kinds, err := v.ctrl.getSchemaObjectKinds(dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*tview.TreeNode, 0, len(kinds))
+
+	for _, kind := range kinds {
+		nodes = append(nodes, tview.NewTreeNode(kind.String()).
+			SetSelectable(true).
+			SetReference(&nodeRef{Type: typeFolder, DB: dbID, Schema: schema, Kind: kind}))
 	}
+
+	return nodes, nil
 }
 
 func (v *mainView) handleKey(event *tcell.EventKey) *tcell.EventKey {
@@ -346,6 +807,11 @@ func (v *mainView) handleKey(event *tcell.EventKey) *tcell.EventKey {
 
 	log.Printf("Handling key %s", keyName)
 
+	// typed characters belong to the query, e.g. ? as query parameter.
+	if event.Key() == tcell.KeyRune && event.Modifiers()&(tcell.ModAlt|tcell.ModCtrl) == 0 && v.queryInput.HasFocus() {
+		return event
+	}
+
 	opName, ok := v.keyMapping[keyName]
 	if !ok {
 		log.Printf("No key mapping found for key %s", keyName)
@@ -380,8 +846,10 @@ func (v *mainView) saveCurrentQuery() {
 }
 
 func (v *mainView) quit() {
-	v.saveCurrentQuery()
-	v.app.Stop()
+	v.confirmOpenTransactions(v.ctrl.openTransactions(), func() {
+		v.saveCurrentQuery()
+		v.app.Stop()
+	})
 }
 
 func (v *mainView) gotoQueryInput() {
@@ -401,11 +869,12 @@ func (v *mainView) nextQueryTab() {
 			v.queryTabIdx++
 			v.queryInput.SetText("", true)
 		} else {
+			v.dropUnsavedTab()
 			v.queryTabIdx = 0
 			v.queryInput.SetText(v.queryTabs[v.queryTabIdx], true)
 		}
 
-		v.updateQueryInputTitle()
+		v.enterTab()
 
 		return
 	}
@@ -419,7 +888,7 @@ func (v *mainView) nextQueryTab() {
 		v.queryInput.SetText("", true)
 	}
 
-	v.updateQueryInputTitle()
+	v.enterTab()
 }
 
 func (v *mainView) prevQueryTab() {
@@ -431,6 +900,8 @@ func (v *mainView) prevQueryTab() {
 	if v.queryTabIdx == len(v.queryTabs) {
 		if currentQuery != "" {
 			v.queryTabs = append(v.queryTabs, currentQuery)
+		} else {
+			v.dropUnsavedTab()
 		}
 	} else {
 		v.queryTabs[v.queryTabIdx] = currentQuery
@@ -443,7 +914,36 @@ func (v *mainView) prevQueryTab() {
 	}
 
 	v.queryInput.SetText(v.queryTabs[v.queryTabIdx], true)
-	v.updateQueryInputTitle()
+	v.enterTab()
+}
+
+// newQueryTab saves the current query and opens a new query tab with query.
+func (v *mainView) newQueryTab(query string) {
+	v.saveCurrentQuery()
+
+	if v.queryTabIdx >= len(v.queryTabs) {
+		v.dropUnsavedTab() // the current tab is empty and is replaced.
+	}
+
+	v.queryTabs = append(v.queryTabs, query)
+	v.queryTabIdx = len(v.queryTabs) - 1
+	v.queryInput.SetText(query, true)
+	v.enterTab()
+}
+
+// dropUnsavedTab discards the state of the empty query tab after the saved
+// query tabs.
+func (v *mainView) dropUnsavedTab() {
+	if len(v.tabs) > len(v.queryTabs) {
+		closeResults(v.tabs[len(v.queryTabs)])
+		v.tabs = v.tabs[:len(v.queryTabs)]
+	}
+}
+
+func closeResults(tab *queryTab) {
+	for _, result := range tab.results {
+		result.close()
+	}
 }
 
 func (v *mainView) closeDB() {
@@ -461,8 +961,34 @@ func (v *mainView) closeDB() {
 		return
 	}
 
+	if v.ctrl.inTransaction(ref.DB) {
+		v.confirmOpenTransactions([]string{ref.DB}, func() {
+			v.closeDBNode(treeNode, ref)
+		})
+
+		return
+	}
+
+	v.closeDBNode(treeNode, ref)
+}
+
+func (v *mainView) closeDBNode(treeNode *tview.TreeNode, ref *nodeRef) {
 	v.dbTree.GetRoot().RemoveChild(treeNode)
 
+	for _, tab := range v.tabs {
+		for _, result := range tab.results {
+			if result.result.dbID == ref.DB {
+				result.close() // an open result would block closing the database.
+			}
+		}
+
+		if tab.dbID == ref.DB {
+			tab.dbID = ""
+		}
+	}
+
+	v.updateResultTableTitle()
+
 	v.setCurrentDB(v.ctrl.closeDatabase(ref.DB))
 }
 
@@ -472,16 +998,22 @@ func (v *mainView) closeQueryTab() {
 	}
 
 	if v.queryTabIdx == len(v.queryTabs) {
+		v.dropUnsavedTab()
 		v.queryTabIdx--
 	} else {
 		v.queryTabs = append(v.queryTabs[:v.queryTabIdx], v.queryTabs[v.queryTabIdx+1:]...)
+		if v.queryTabIdx < len(v.tabs) {
+			closeResults(v.tabs[v.queryTabIdx])
+			v.tabs = append(v.tabs[:v.queryTabIdx], v.tabs[v.queryTabIdx+1:]...)
+		}
+
 		if v.queryTabIdx > 0 {
 			v.queryTabIdx--
 		}
 	}
 
 	v.queryInput.SetText(v.queryTabs[v.queryTabIdx], true)
-	v.updateQueryInputTitle()
+	v.enterTab()
 }
 
 func (v *mainView) gotoTree() {
@@ -505,193 +1037,1958 @@ func (v *mainView) setCurrentDatabase() {
 }
 
 func (v *mainView) execQuery() {
-	if v.currentDB == "" {
-		v.showError("No database has been selected")
+	v.runQuery(v.queryInput.GetText())
+}
 
-		return
+// execStatement executes the selected text, or the statement at the cursor
+// if nothing is selected.
+func (v *mainView) execStatement() {
+	if query, ok := v.currentStatement(); ok {
+		v.runQuery(query)
 	}
+}
 
-	go func() {
-		v.startActivityGauge()
-		defer v.stopActivityGauge()
+// currentStatement returns the selected text, or the statement at the
+// cursor if nothing is selected. An error is shown if there is neither.
+func (v *mainView) currentStatement() (string, bool) {
+	text, start, _ := v.queryInput.GetSelection()
+	if text != "" {
+		return text, true
+	}
 
-		if err := v.ctrl.execQuery(v.currentDB, v.queryInput.GetText()); err != nil {
-			v.showError("Query failed: %v", err)
+	stmt, ok := statementAt(v.queryInput.GetText(), start, v.queryInput.dialect)
+	if !ok {
+		v.showError("No statement at cursor")
 
-			return
-		}
+		return "", false
+	}
 
-		v.app.SetFocus(v.resultTable)
-	}()
+	return stmt.Text, true
 }
 
-func (v *mainView) addDatabaseDialog() {
-	selectedOption := ""
-	form := tview.NewForm().AddDropDown("Driver", supportedDriverList(), 0, func(option string, optionIndex int) {
-		selectedOption = option
-	}).AddButton("Next", func() {
-		v.dbParamsDialog(selectedOption)
-	}).AddButton("Cancel", func() {
-		v.showMainView()
-	})
+// runQuery executes query on the current database. If the query has
+// parameters, their values are asked for first.
+func (v *mainView) runQuery(query string) {
+	if v.currentDB == "" {
+		v.showError("No database has been selected")
 
-	form.SetBorder(true).SetTitle("Add Database - Choose Driver")
+		return
+	}
 
-	v.app.SetRoot(form, true)
+	names := queryParamNames(query, v.queryInput.dialect)
+	if len(names) == 0 {
+		v.startQuery(query, nil)
+
+		return
+	}
+
+	v.queryParamsDialog(names, func(params map[string]interface{}) {
+		v.startQuery(query, params)
+	})
 }
 
-func (v *mainView) dbParamsDialog(driver string) {
-	drv := supportedDrivers[driver]
+// queryParamsDialog asks for the values of query parameters, prefilled with
+// the values last used in the current query tab. Values are passed as text,
+// unless the user asks for numbers to be passed as numbers.
+func (v *mainView) queryParamsDialog(names []string, run func(params map[string]interface{})) {
+	tab := v.tab()
+	if tab.params == nil {
+		tab.params = make(map[string]string)
+	}
+
+	lastValues := tab.params
+
 	form := tview.NewForm()
-	drv.AddInputFields(form)
-	form.AddButton("Add Database", func() {
-		params := drv.GetConnectParams(form)
-		if err := v.ctrl.openDatabase(driver, params); err != nil {
-			log.Printf("Opening database %s %+v failed: %v", driver, params, err)
+	for _, name := range names {
+		form.AddInputField(name, lastValues[name], 60, nil, nil)
+	}
+
+	form.AddCheckbox("Pass numbers as numbers", tab.numbers, nil)
+
+	form.AddButton("Run", func() {
+		tab.numbers = form.GetFormItem(len(names)).(*tview.Checkbox).IsChecked()
+
+		params := make(map[string]interface{}, len(names))
+		for idx, name := range names {
+			value := form.GetFormItem(idx).(*tview.InputField).GetText()
+			lastValues[name] = value
+
+			if tab.numbers {
+				params[name] = paramValue(value)
+			} else {
+				params[name] = value
+			}
 		}
+
 		v.showMainView()
+		run(params)
 	}).AddButton("Cancel", func() {
 		v.showMainView()
+		v.app.SetFocus(v.queryInput)
 	})
-	form.SetBorder(true).SetTitle(fmt.Sprintf("Add Database - %s Configuration", drv.Name))
+	form.SetCancelFunc(func() {
+		v.showMainView()
+		v.app.SetFocus(v.queryInput)
+	})
+	form.SetBorder(true).SetTitle("Query Parameters")
 	v.app.SetRoot(form, true)
 }
 
-func (v *mainView) addDatabase(dbID, dbName string) {
-	v.dbRootNode.AddChild(tview.NewTreeNode(dbName).SetSelectable(true).SetReference(&nodeRef{Type: typeDB, DB: dbID}))
+// startQuery executes query on the current database in the background.
+func (v *mainView) startQuery(query string, params map[string]interface{}) {
+	v.queryMtx.Lock()
+	defer v.queryMtx.Unlock()
 
-	if v.currentDB == "" { // if no database been selected yet, simply set it to database that is being added.
-		v.setCurrentDB(dbID)
+	if v.cancelQuery != nil {
+		v.showError("A query is already running")
+
+		return
 	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancelQuery = cancel
+	v.runningTab = v.tab()
+
+	dbID := v.currentDB
+
+	go func() {
+		v.startActivityGauge()
+		defer v.stopActivityGauge()
+
+		defer func() {
+			v.queryMtx.Lock()
+			v.cancelQuery = nil
+			v.runningTab = nil
+			v.queryMtx.Unlock()
+			v.app.QueueUpdateDraw(v.updateContextField) // statements may have started or finished a transaction.
+		}()
+
+		// on success, ctx stays alive until the result is closed.
+		if err := v.ctrl.execQuery(ctx, dbID, query, params); err != nil {
+			if ctx.Err() != nil {
+				log.Printf("Query was cancelled: %v", err)
+				v.app.QueueUpdateDraw(func() {
+					v.activityPlaceholder.SetText("Query cancelled")
+				})
+
+				return
+			}
+
+			cancel()
+			v.app.QueueUpdateDraw(func() {
+				v.showError("Query failed: %v", err)
+			})
+
+			return
+		}
+
+		v.app.QueueUpdateDraw(func() {
+			v.app.SetFocus(v.resultTable)
+		})
+	}()
 }
 
-func (v *mainView) downloadResult() {
+// cancelRunningQuery cancels the running query. If no query is running, the
+// queries of the results of the current tab that haven't been fetched
+// completely are cancelled instead, which stops fetching more rows.
+func (v *mainView) cancelRunningQuery() {
+	v.queryMtx.Lock()
+	defer v.queryMtx.Unlock()
+
+	if v.cancelQuery == nil {
+		cancelled := false
+
+		for _, result := range v.tab().results {
+			if result.cancelFetch() {
+				cancelled = true
+			}
+		}
+
+		if cancelled {
+			log.Printf("Cancelled fetching results")
+			v.activityPlaceholder.SetText("Fetching results cancelled")
+		}
+
+		return
+	}
+
+	log.Printf("Cancelling running query")
+
+	v.activityGauge.SetPgBgColor(tcell.ColorRed) // indicate that the query is being cancelled
+	v.cancelQuery()
+}
+
+func (v *mainView) addDatabaseDialog() {
+	profiles := v.ctrl.getProfiles()
+	profileNames := []string{"New connection"}
+
+	for _, profile := range profiles {
+		profileNames = append(profileNames, profile.Name)
+	}
+
+	selectedProfile := 0
+	selectedOption := ""
 	form := tview.NewForm()
-	form.AddInputField("File", time.Now().Format("result_20060102_150405.csv"), 80, nil, nil)
-	form.AddButton("Save", func() {
-		filename := form.GetFormItem(0).(*tview.InputField).GetText()
 
-		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-		if err != nil {
-			v.showError("Saving file failed: %v", err)
+	if len(profiles) > 0 {
+		form.AddDropDown("Profile", profileNames, 0, func(option string, optionIndex int) {
+			selectedProfile = optionIndex
+		})
+	}
+
+	form.AddDropDown("Driver", supportedDriverList(), 0, func(option string, optionIndex int) {
+		selectedOption = option
+	}).AddButton("Next", func() {
+		if selectedProfile == 0 {
+			v.dbParamsDialog(selectedOption)
 
 			return
 		}
-		defer f.Close()
 
-		csvWriter := csv.NewWriter(f)
+		if err := v.ctrl.openProfile(profileNames[selectedProfile]); err != nil {
+			v.showError("Opening database %s failed: %v", profileNames[selectedProfile], err)
 
-		for rowIdx := 0; rowIdx < v.resultTable.GetRowCount(); rowIdx++ {
-			var fields []string
-			for colIdx := 0; colIdx < v.resultTable.GetColumnCount(); colIdx++ {
-				fields = append(fields, v.resultTable.GetCell(rowIdx, colIdx).Text)
-			}
-			if err := csvWriter.Write(fields); err != nil {
-				v.showError("Writing file failed: %v", err)
+			return
+		}
 
-				return
-			}
+		v.showMainView()
+	}).AddButton("Cancel", func() {
+		v.showMainView()
+	})
+
+	form.SetBorder(true).SetTitle("Add Database - Choose Profile or Driver")
+
+	v.app.SetRoot(form, true)
+}
+
+func (v *mainView) dbParamsDialog(driver string) {
+	drv := supportedDrivers[driver]
+	form := tview.NewForm()
+	drv.AddInputFields(form)
+
+	profileField := tview.NewInputField().SetLabel("Profile Name").SetFieldWidth(30)
+	form.AddFormItem(profileField)
+
+	form.AddButton("Add Database", func() {
+		params := drv.GetConnectParams(form)
+		if err := v.ctrl.checkSecrets(driver, params); err != nil {
+			v.showError("Adding database failed: %v", err)
+
+			return
+		}
+
+		if err := v.ctrl.openDatabase(driver, params, ""); err != nil {
+			log.Printf("Opening database %s %+v failed: %v", driver, params, err)
+		}
+		v.showMainView()
+	}).AddButton("Save as Profile", func() {
+		profile := connectionProfile{
+			Name:          profileField.GetText(),
+			Driver:        driver,
+			ConnectParams: drv.GetConnectParams(form),
+		}
+
+		if profile.Name == "" {
+			v.showError("No profile name has been entered")
+
+			return
+		}
+
+		if err := v.ctrl.saveProfile(profile); err != nil {
+			v.showError("Saving profile %s failed: %v", profile.Name, err)
+
+			return
+		}
+
+		if err := v.ctrl.openProfile(profile.Name); err != nil {
+			log.Printf("Opening database %s failed: %v", profile.Name, err)
 		}
+		v.showMainView()
+	}).AddButton("Cancel", func() {
+		v.showMainView()
+	})
+	form.SetBorder(true).SetTitle(fmt.Sprintf("Add Database - %s Configuration", drv.Name))
+	v.app.SetRoot(form, true)
+}
+
+func (v *mainView) addDatabase(dbID, dbName string) {
+	v.dbRootNode.AddChild(tview.NewTreeNode(dbName).SetSelectable(true).SetReference(&nodeRef{Type: typeDB, DB: dbID}))
+
+	if v.currentDB == "" { // if no database been selected yet, simply set it to database that is being added.
+		v.setCurrentDB(dbID)
+	}
+}
+
+func (v *mainView) downloadResult() {
+	result := v.currentResult()
+	if result == nil || result.result.rows == nil {
+		v.showError("No result to export")
+
+		return
+	}
+
+	format := exportFormats[0]
 
-		csvWriter.Flush()
+	form := tview.NewForm()
+	form.AddDropDown("Format", exportFormatNames(), 0, nil)
+	form.AddInputField("File", time.Now().Format("result_20060102_150405.")+format.Extension, 80, nil, nil)
+	form.AddInputField("Table Name", "", 40, nil, nil)
+
+	fileField := form.GetFormItem(1).(*tview.InputField)
+	tableField := form.GetFormItem(2).(*tview.InputField)
+
+	form.GetFormItem(0).(*tview.DropDown).SetSelectedFunc(func(text string, index int) {
+		filename := strings.TrimSuffix(fileField.GetText(), "."+format.Extension)
+		format = exportFormats[index]
+		fileField.SetText(filename + "." + format.Extension)
+	})
 
-		if err := csvWriter.Error(); err != nil {
-			log.Printf("CSV writer indicated error: %v", err)
+	form.AddButton("Save", func() {
+		driver := v.ctrl.getDriver(result.result.dbID)
+		opts := exportOptions{
+			TableName: tableField.GetText(),
+			QuoteIdent: func(ident string) string {
+				return quoteIdentifier(driver, ident)
+			},
 		}
 
 		v.showMainView()
+		v.exportInBackground(result, fileField.GetText(), format, opts)
 	}).AddButton("Cancel", func() {
 		v.showMainView()
 	})
-	form.SetBorder(true).SetTitle("Store Result")
+	form.SetBorder(true).SetTitle("Export Result")
 	v.app.SetRoot(form, true)
 }
 
+// exportInBackground fetches the remaining rows of result and exports all
+// rows to a file. Fetching the rows can be cancelled with cancel-query.
+func (v *mainView) exportInBackground(result *resultTableContent, filename string, format exportFormat, opts exportOptions) {
+	v.queryMtx.Lock()
+	defer v.queryMtx.Unlock()
+
+	if v.cancelQuery != nil {
+		v.showError("A query is already running")
+
+		return
+	}
+
+	v.cancelQuery = func() {
+		result.cancelFetch()
+	}
+
+	go func() {
+		v.startActivityGauge()
+		defer v.stopActivityGauge()
+
+		err := result.fetchAll()
+		if err != nil {
+			err = fmt.Errorf("fetching result failed: %w", err)
+		} else {
+			err = exportResult(filename, format, opts, result.result.columns, result.allRows())
+		}
+
+		v.queryMtx.Lock()
+		v.cancelQuery = nil
+		v.queryMtx.Unlock()
+
+		v.app.QueueUpdateDraw(func() {
+			v.updateResultTableTitle()
+
+			switch {
+			case errors.Is(err, errFetchCancelled):
+				v.activityPlaceholder.SetText("Export cancelled")
+			case err != nil:
+				v.showError("Exporting result failed: %v", err)
+			default:
+				v.activityPlaceholder.SetText("Exported result to " + filename)
+			}
+		})
+	}()
+}
+
+// setCurrentDB makes a database the current database and binds the current
+// query tab to it.
 func (v *mainView) setCurrentDB(dbID string) {
 	v.currentDB = dbID
+	v.tab().dbID = dbID
+
+	v.queryInput.setDialect(driverDialect(v.ctrl.getDriver(dbID)))
+
+	v.updateContextField()
+	v.updateQueryInputTitle()
+}
+
+func (v *mainView) updateContextField() {
 	if v.currentDB == "" {
 		v.contextField.SetText("No DB selected!")
-	} else {
-		dbName := v.ctrl.getDatabaseName(dbID)
-		v.contextField.SetText("Current DB: " + dbName)
+
+		return
+	}
+
+	text := "Current DB: " + v.ctrl.getDatabaseName(v.currentDB)
+	if v.ctrl.inTransaction(v.currentDB) {
+		text += " [TX]"
 	}
+
+	v.contextField.SetText(text)
 }
 
-func (v *mainView) clearResultTable() {
-	v.resultTable.Clear()
-	v.currentResultTableRow = 1
+func (v *mainView) beginTx() {
+	if v.currentDB == "" {
+		v.showError("No database has been selected")
+
+		return
+	}
+
+	if err := v.ctrl.beginTx(v.currentDB); err != nil {
+		v.showError("Starting transaction failed: %v", err)
+	}
+
+	v.updateContextField()
 }
 
-func (v *mainView) setResultTableColumns(cols []string) {
-	for idx, col := range cols {
-		v.resultTable.SetCell(0, idx, tview.NewTableCell(col).SetAttributes(tcell.AttrBold))
+func (v *mainView) commitTx() {
+	if v.currentDB == "" {
+		v.showError("No database has been selected")
+
+		return
+	}
+
+	if err := v.ctrl.commitTx(v.currentDB); err != nil {
+		v.showError("Committing transaction failed: %v", err)
 	}
+
+	v.updateContextField()
 }
 
-func (v *mainView) addResultTableRow(values []string) {
-	for idx, val := range values {
-		v.resultTable.SetCell(v.currentResultTableRow, idx, tview.NewTableCell(val))
+func (v *mainView) rollbackTx() {
+	if v.currentDB == "" {
+		v.showError("No database has been selected")
+
+		return
+	}
+
+	if err := v.ctrl.rollbackTx(v.currentDB); err != nil {
+		v.showError("Rolling back transaction failed: %v", err)
 	}
-	v.currentResultTableRow++
+
+	v.updateContextField()
 }
 
-func (v *mainView) showError(s string, args ...any) {
+// confirmOpenTransactions asks the user whether the open transactions of
+// the databases dbIDs shall be committed or rolled back, and then calls
+// proceed. If there are no open transactions, proceed is called right away.
+func (v *mainView) confirmOpenTransactions(dbIDs []string, proceed func()) {
+	if len(dbIDs) == 0 {
+		proceed()
+
+		return
+	}
+
+	names := make([]string, 0, len(dbIDs))
+	for _, dbID := range dbIDs {
+		names = append(names, v.ctrl.getDatabaseName(dbID))
+	}
+
 	modal := tview.NewModal().
-		SetText(fmt.Sprintf(s, args...)).
-		AddButtons([]string{"OK"}).
+		SetText(fmt.Sprintf("There are open transactions on: %s", strings.Join(names, ", "))).
+		AddButtons([]string{"Commit", "Rollback", "Cancel"}).
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			var finishTx func(dbID string) error
+
+			switch buttonLabel {
+			case "Commit":
+				finishTx = v.ctrl.commitTx
+			case "Rollback":
+				finishTx = v.ctrl.rollbackTx
+			default:
+				v.showMainView()
+
+				return
+			}
+
+			for _, dbID := range dbIDs {
+				if err := finishTx(dbID); err != nil {
+					v.showError("Finishing transaction on %s failed: %v", v.ctrl.getDatabaseName(dbID), err)
+
+					return
+				}
+			}
+
+			v.updateContextField()
 			v.showMainView()
+			proceed()
 		})
 
 	v.app.SetRoot(modal, false)
 }
 
-func (v *mainView) showHelp() {
-	helpScreen := tview.NewTable()
-	helpScreen.SetBorder(true).SetTitle("Help (press ESC to exit)")
+// clearQueryResults removes the results of the tab of the running query.
+func (v *mainView) clearQueryResults() {
+	tab := v.resultTab()
 
-	type keyMappingConfig struct {
-		Key         string
-		Operation   string
-		Description string
-	}
+	v.app.QueueUpdateDraw(func() {
+		closeResults(tab)
 
-	keyMappings := []keyMappingConfig{}
+		tab.results = nil
+		tab.resultIdx = 0
 
-	for keyName, opName := range v.keyMapping {
-		desc := v.operationMapping[opName].Description
+		if tab == v.tab() {
+			v.resultTable.SetContent(nil)
+			v.updateResultTableTitle()
+		}
+	})
+}
 
-		keyMappings = append(keyMappings, keyMappingConfig{Key: keyName, Operation: opName, Description: desc})
+// resultTab returns the tab that results of the running query belong to.
+func (v *mainView) resultTab() *queryTab {
+	v.queryMtx.Lock()
+	defer v.queryMtx.Unlock()
+
+	return v.runningTab
+}
+
+// addQueryResult fetches the first page of rows from result, adds it to
+// the list of results of the tab of the running query and shows it in the
+// result table if that tab is the current tab.
+func (v *mainView) addQueryResult(result *queryResult) error {
+	tab := v.resultTab()
+
+	content := newResultTableContent(result, v.pageSize, v.format, func() {
+		v.app.QueueUpdateDraw(v.updateResultTableTitle)
+	})
+
+	if err := content.fetchPage(); err != nil {
+		content.close()
+
+		return err
 	}
 
-	sort.Slice(keyMappings, func(i, j int) bool {
-		return keyMappings[i].Operation < keyMappings[j].Operation
+	v.app.QueueUpdateDraw(func() {
+		tab.results = append(tab.results, content)
+		tab.resultIdx = len(tab.results) - 1
+
+		if tab == v.tab() {
+			v.showResult(tab.resultIdx)
+		}
 	})
 
-	for idx, hdr := range []string{"Key", "Operation", "Description"} {
-		helpScreen.SetCell(0, idx, tview.NewTableCell(hdr).SetAttributes(tcell.AttrBold))
+	return nil
+}
+
+func (v *mainView) currentResult() *resultTableContent {
+	tab := v.tab()
+	if tab.resultIdx >= len(tab.results) {
+		return nil
 	}
 
-	for idx, keyMapping := range keyMappings {
-		helpScreen.SetCellSimple(idx+1, 0, keyMapping.Key)
-		helpScreen.SetCellSimple(idx+1, 1, keyMapping.Operation)
-		helpScreen.SetCellSimple(idx+1, 2, keyMapping.Description)
+	return tab.results[tab.resultIdx]
+}
+
+func (v *mainView) showResult(idx int) {
+	v.tab().resultIdx = idx
+
+	if result := v.currentResult(); result != nil {
+		v.resultTable.SetContent(result)
+	} else {
+		v.resultTable.SetContent(nil) // a nil *resultTableContent would be a non-nil content.
 	}
 
-	helpScreen.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyESC {
-			v.showMainView()
+	v.resultTable.ScrollToBeginning()
 
-			return nil
-		}
+	if result := v.currentResult(); result != nil && result.result.rows != nil {
+		v.resultTable.Select(1, 0) // the first row below the header
+	} else {
+		v.resultTable.Select(0, 0)
+	}
+	v.updateResultTableTitle()
+}
 
-		return event
-	})
+func (v *mainView) nextResult() {
+	tab := v.tab()
+	if len(tab.results) == 0 {
+		return
+	}
 
-	v.app.SetRoot(helpScreen, true)
+	v.showResult((tab.resultIdx + 1) % len(tab.results))
 }
 
-func (v *mainView) run() error {
+func (v *mainView) prevResult() {
+	tab := v.tab()
+	if len(tab.results) == 0 {
+		return
+	}
+
+	v.showResult((tab.resultIdx + len(tab.results) - 1) % len(tab.results))
+}
+
+func (v *mainView) updateResultTableTitle() {
+	result := v.currentResult()
+	if result == nil {
+		v.resultTable.SetTitle("Result")
+
+		return
+	}
+
+	tab := v.tab()
+	if len(tab.results) == 1 {
+		v.resultTable.SetTitle(fmt.Sprintf("Result (%s)", result.status()))
+
+		return
+	}
+
+	v.resultTable.SetTitle(fmt.Sprintf("Result %d/%d (%s)", tab.resultIdx+1, len(tab.results), result.status()))
+}
+
+// withEditableResult calls f with the current result once it can be
+// edited. When a result is edited for the first time, it is checked that
+// its rows belong to a table with a primary key.
+func (v *mainView) withEditableResult(f func(result *resultTableContent)) {
+	result := v.currentResult()
+	if result == nil {
+		v.showError("No result to edit")
+
+		return
+	}
+
+	if result.isEditing() {
+		f(result)
+
+		return
+	}
+
+	name, ok := result.editable()
+	if !ok {
+		v.showError("Only results of SELECT statements from a single table can be edited")
+
+		return
+	}
+
+	dbID := result.result.dbID
+
+	go func() {
+		v.startActivityGauge()
+		defer v.stopActivityGauge()
+
+		table, columns, primaryKey, err := v.ctrl.getEditInfo(dbID, name)
+
+		var edit *resultEdit
+		if err == nil {
+			edit, err = newResultEdit(table, result.result.columns, result.result.selected, columns, primaryKey)
+		}
+
+		v.app.QueueUpdateDraw(func() {
+			if err != nil {
+				v.showError("Editing result failed: %v", err)
+
+				return
+			}
+
+			result.startEdit(edit)
+			f(result)
+		})
+	}()
+}
+
+// editCell edits the value of the selected cell of the result table.
+func (v *mainView) editCell() {
+	v.withEditableResult(func(result *resultTableContent) {
+		row, column := v.resultTable.GetSelection()
+
+		value, ok := result.cellValue(row, column)
+		if !ok {
+			return
+		}
+
+		form := tview.NewForm()
+		form.AddInputField("Value", valueString(value, ""), 80, nil, func(text string) {
+			form.GetFormItem(1).(*tview.Checkbox).SetChecked(false) // entering a value replaces NULL.
+		})
+		form.AddCheckbox("NULL", value == nil, nil)
+
+		form.AddButton("Save", func() {
+			var newValue interface{}
+			if !form.GetFormItem(1).(*tview.Checkbox).IsChecked() {
+				newValue = parseCellValue(form.GetFormItem(0).(*tview.InputField).GetText(), value)
+			}
+
+			if err := result.setCellValue(row, column, newValue); err != nil {
+				v.showError("Editing cell failed: %v", err)
+
+				return
+			}
+
+			v.showMainView()
+			v.app.SetFocus(v.resultTable)
+			v.updateResultTableTitle()
+		}).AddButton("Cancel", func() {
+			v.showMainView()
+			v.app.SetFocus(v.resultTable)
+		})
+		form.SetBorder(true).SetTitle("Edit " + result.result.columns[column])
+		v.app.SetRoot(form, true)
+	})
+}
+
+func (v *mainView) insertRow() {
+	v.withEditableResult(func(result *resultTableContent) {
+		row := result.insertRow()
+
+		v.app.SetFocus(v.resultTable)
+		v.resultTable.Select(row, 0)
+		v.updateResultTableTitle()
+	})
+}
+
+func (v *mainView) deleteRow() {
+	v.withEditableResult(func(result *resultTableContent) {
+		row, _ := v.resultTable.GetSelection()
+		result.toggleDeleted(row)
+		v.updateResultTableTitle()
+	})
+}
+
+func (v *mainView) discardChanges() {
+	if result := v.currentResult(); result != nil {
+		result.discardEdit()
+		v.updateResultTableTitle()
+	}
+}
+
+// applyChanges shows the statements that apply the pending changes of the
+// current result, and runs them if confirmed.
+func (v *mainView) applyChanges() {
+	result := v.currentResult()
+	if result == nil {
+		v.showError("No pending changes")
+
+		return
+	}
+
+	dbID := result.result.dbID
+	driver := v.ctrl.getDriver(dbID)
+
+	stmts := result.editStatements(func(ident string) string {
+		return quoteIdentifier(driver, ident)
+	})
+	if len(stmts) == 0 {
+		v.showError("No pending changes")
+
+		return
+	}
+
+	previews := make([]string, 0, len(stmts))
+	for _, stmt := range stmts {
+		previews = append(previews, stmt.preview())
+	}
+
+	preview := tview.NewTextView().SetText(strings.Join(previews, "\n"))
+	preview.SetBorder(true).SetTitle("Pending Changes")
+
+	buttons := tview.NewForm().
+		AddButton("Apply", func() {
+			v.showMainView()
+			v.runChanges(result, dbID, stmts)
+		}).
+		AddButton("Cancel", func() {
+			v.showMainView()
+		})
+	buttons.SetCancelFunc(v.showMainView)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(preview, 0, 1, false).
+		AddItem(buttons, 3, 0, true)
+
+	v.app.SetRoot(layout, true)
+}
+
+// runChanges applies the statements of an edit in the background. Like a
+// query, it can't run while another query is running, as both could use the
+// same transaction, and it can be cancelled with cancel-query. Open cursors
+// on the database are closed first, as they could keep the changes from
+// being written.
+func (v *mainView) runChanges(result *resultTableContent, dbID string, stmts []editStatement) {
+	v.queryMtx.Lock()
+	defer v.queryMtx.Unlock()
+
+	if v.cancelQuery != nil {
+		v.showError("A query is already running")
+
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancelQuery = cancel
+
+	if !v.ctrl.inTransaction(dbID) {
+		for _, tab := range v.tabs {
+			for _, r := range tab.results {
+				if r.result.dbID == dbID {
+					r.close()
+				}
+			}
+		}
+	}
+
+	go func() {
+		v.startActivityGauge()
+		defer v.stopActivityGauge()
+
+		err := v.ctrl.applyChanges(ctx, dbID, stmts)
+		cancelled := ctx.Err() != nil
+
+		v.queryMtx.Lock()
+		v.cancelQuery = nil
+		v.queryMtx.Unlock()
+		cancel()
+
+		v.app.QueueUpdateDraw(func() {
+			switch {
+			case err != nil && cancelled:
+				v.activityPlaceholder.SetText("Applying changes cancelled")
+
+				return
+			case err != nil:
+				v.showError("Applying changes failed: %v", err)
+
+				return
+			}
+
+			result.commitEdit()
+			v.updateResultTableTitle()
+			v.updateContextField()
+		})
+	}()
+}
+
+// searchResult asks for a text to search in the loaded rows of the current
+// result. While typing, the first matching cell at or after the selected
+// cell is selected and all matches are highlighted.
+func (v *mainView) searchResult() {
+	result := v.currentResult()
+	if result == nil || result.result.rows == nil {
+		v.showError("No result to search")
+
+		return
+	}
+
+	startRow, startColumn := v.resultTable.GetSelection()
+
+	input := tview.NewInputField().SetLabel("Search: ")
+	input.SetChangedFunc(func(text string) {
+		result.setSearch(text)
+
+		if row, column, ok := result.findMatch(startRow, startColumn, false, true); ok {
+			v.resultTable.Select(row, column)
+		}
+	})
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			result.setSearch("")
+			v.resultTable.Select(startRow, startColumn)
+		}
+
+		v.showMainView()
+		v.app.SetFocus(v.resultTable)
+	})
+
+	// the search line is shown outside of the main layout so that typing
+	// isn't interpreted as key bindings.
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(v.layout, 0, 1, false).
+		AddItem(input, 1, 0, true)
+
+	v.app.SetRoot(layout, true)
+}
+
+func (v *mainView) nextMatch() {
+	v.gotoMatch(false)
+}
+
+func (v *mainView) prevMatch() {
+	v.gotoMatch(true)
+}
+
+func (v *mainView) gotoMatch(backwards bool) {
+	result := v.currentResult()
+	if result == nil {
+		return
+	}
+
+	row, column := v.resultTable.GetSelection()
+	if row, column, ok := result.findMatch(row, column, backwards, false); ok {
+		v.app.SetFocus(v.resultTable)
+		v.resultTable.Select(row, column)
+	}
+}
+
+// sortResult sorts the loaded rows of the current result by the selected
+// column. Repeating it reverses the order and then restores the original
+// order.
+func (v *mainView) sortResult() {
+	result := v.currentResult()
+	if result == nil || result.result.rows == nil {
+		return
+	}
+
+	_, column := v.resultTable.GetSelection()
+	result.sortBy(column)
+	v.updateResultTableTitle()
+}
+
+// filterResult asks for a filter expression for the loaded rows of the
+// current result.
+func (v *mainView) filterResult() {
+	result := v.currentResult()
+	if result == nil || result.result.rows == nil {
+		v.showError("No result to filter")
+
+		return
+	}
+
+	done := func() {
+		v.showMainView()
+		v.app.SetFocus(v.resultTable)
+		v.resultTable.Select(1, 0)
+		v.updateResultTableTitle()
+	}
+
+	form := tview.NewForm()
+	form.AddInputField("Filter", result.filterText(), 80, nil, nil)
+	form.AddButton("Apply", func() {
+		text := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		if text == "" {
+			result.setFilter(nil)
+			done()
+
+			return
+		}
+
+		filter, err := parseFilter(text, result.result.columns)
+		if err != nil {
+			v.showError("Invalid filter: %v", err)
+
+			return
+		}
+
+		result.setFilter(filter)
+		done()
+	}).AddButton("Clear", func() {
+		result.setFilter(nil)
+		done()
+	}).AddButton("Cancel", func() {
+		v.showMainView()
+		v.app.SetFocus(v.resultTable)
+	})
+	form.SetBorder(true).SetTitle("Filter Rows (e.g. price >= 10 AND name LIKE 'a%')")
+	v.app.SetRoot(form, true)
+}
+
+// showRow shows the selected row of the current result with one line per
+// column, and the full value of the selected column below, with JSON and
+// XML indented.
+func (v *mainView) showRow() {
+	result := v.currentResult()
+	if result == nil || result.result.rows == nil {
+		v.showError("No result to show")
+
+		return
+	}
+
+	row, _ := v.resultTable.GetSelection()
+	if _, ok := result.rowValues(row); !ok {
+		v.showError("No row selected")
+
+		return
+	}
+
+	var values []interface{}
+
+	fields := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	fields.SetBorder(true)
+
+	valueView := tview.NewTextView().SetWrap(true)
+	valueView.SetBorder(true)
+
+	valueText := func(field int) string {
+		if field < 0 || field >= len(values) || values[field] == nil {
+			return "NULL"
+		}
+
+		if text, ok := values[field].(string); ok {
+			return prettyText(text, result.result.types[field])
+		}
+
+		return result.text(values[field])
+	}
+
+	fields.SetSelectionChangedFunc(func(field, _ int) {
+		field-- // the first row is the header.
+		if field >= 0 && field < len(values) {
+			valueView.SetText(valueText(field)).ScrollToBeginning()
+			valueView.SetTitle(tview.Escape(result.result.columns[field]))
+		}
+	})
+
+	showValues := func(r int) bool {
+		rowValues, ok := result.rowValues(r)
+		if !ok {
+			return false
+		}
+
+		row, values = r, rowValues
+		field, _ := fields.GetSelection()
+
+		fields.Clear()
+
+		for col, header := range []string{"Column", "Type", "Value"} {
+			fields.SetCell(0, col, tview.NewTableCell(header).SetAttributes(tcell.AttrBold).SetSelectable(false))
+		}
+
+		for idx, name := range result.result.columns {
+			fields.SetCellSimple(idx+1, 0, tview.Escape(name))
+			fields.SetCellSimple(idx+1, 1, tview.Escape(result.result.types[idx]))
+			fields.SetCell(idx+1, 2, result.format.cell(values[idx]).SetMaxWidth(80))
+		}
+
+		fields.SetTitle(fmt.Sprintf("Row %d (Ctrl-N/Ctrl-P: next/previous row, y: copy value, ESC: exit)", row))
+
+		if field < 1 {
+			field = 1
+		}
+
+		fields.Select(field, 0)
+
+		return true
+	}
+
+	fields.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape:
+			v.showMainView()
+			v.app.SetFocus(v.resultTable)
+			v.resultTable.Select(row, 0)
+		case event.Key() == tcell.KeyCtrlN:
+			showValues(row + 1)
+		case event.Key() == tcell.KeyCtrlP:
+			if row > 1 {
+				showValues(row - 1)
+			}
+		case event.Key() == tcell.KeyRune && event.Rune() == 'y':
+			if field, _ := fields.GetSelection(); field > 0 && values[field-1] != nil {
+				v.copyToClipboard(valueText(field - 1))
+			} else {
+				v.copyToClipboard("")
+			}
+		default:
+			return event
+		}
+
+		return nil
+	})
+
+	showValues(row)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(fields, 0, 1, true).
+		AddItem(valueView, 0, 1, false)
+
+	v.app.SetRoot(layout, true)
+}
+
+// markCells starts a selection of cells in the current result, which
+// extends to the selected cell until it is ended by marking again.
+func (v *mainView) markCells() {
+	result := v.currentResult()
+	if result == nil || result.result.rows == nil {
+		return
+	}
+
+	row, column := v.resultTable.GetSelection()
+	result.toggleMark(row, column)
+	v.app.SetFocus(v.resultTable)
+}
+
+// copyResult asks what to copy from the current result and in which
+// format, and copies it to the clipboard.
+func (v *mainView) copyResult() {
+	result := v.currentResult()
+	if result == nil || result.result.rows == nil {
+		v.showError("No result to copy")
+
+		return
+	}
+
+	row, column := v.resultTable.GetSelection()
+	lastRow, lastColumn := result.lastRow(), len(result.result.columns)-1
+
+	form := tview.NewForm()
+	form.AddDropDown("Copy", copyScopeNames, int(v.copyScope), nil)
+	form.AddDropDown("Format", copyFormatNames(), v.copyFormat, nil)
+	form.AddButton("Copy", func() {
+		scope, _ := form.GetFormItem(0).(*tview.DropDown).GetCurrentOption()
+		formatIdx, _ := form.GetFormItem(1).(*tview.DropDown).GetCurrentOption()
+		v.copyScope, v.copyFormat = copyScope(scope), formatIdx
+
+		var top, left, bottom, right int
+
+		switch v.copyScope {
+		case copyCell:
+			top, left, bottom, right = row, column, row, column
+		case copyRow:
+			top, left, bottom, right = row, 0, row, lastColumn
+		case copyColumn:
+			top, left, bottom, right = 1, column, lastRow, column
+		case copySelection:
+			top, left, bottom, right = result.selection()
+		case copyAllRows:
+			top, left, bottom, right = 1, 0, lastRow, lastColumn
+		}
+
+		columns, rows := result.cellRange(top, left, bottom, right)
+		if len(rows) == 0 {
+			v.showError("Nothing to copy")
+
+			return
+		}
+
+		text, err := copyFormats[formatIdx].Format(columns, rows)
+		if err != nil {
+			v.showError("Formatting values failed: %v", err)
+
+			return
+		}
+
+		v.showMainView()
+		v.app.SetFocus(v.resultTable)
+		v.copyToClipboard(text)
+	}).AddButton("Cancel", func() {
+		v.showMainView()
+		v.app.SetFocus(v.resultTable)
+	})
+	form.SetCancelFunc(func() {
+		v.showMainView()
+		v.app.SetFocus(v.resultTable)
+	})
+	form.SetBorder(true).SetTitle("Copy to Clipboard")
+	v.app.SetRoot(form, true)
+}
+
+// loadSnippets shows the snippets of the snippet library in the snippet
+// tree, with a folder node for each directory.
+func (v *mainView) loadSnippets() {
+	snippets, err := v.ctrl.getSnippets()
+	if err != nil {
+		log.Printf("Loading snippets failed: %v", err)
+	}
+
+	v.snippetRootNode.ClearChildren()
+
+	folders := map[string]*tview.TreeNode{"": v.snippetRootNode}
+
+	var folderNode func(dir string) *tview.TreeNode
+	folderNode = func(dir string) *tview.TreeNode {
+		if node, ok := folders[dir]; ok {
+			return node
+		}
+
+		parent, name := "", dir
+		if idx := strings.LastIndexByte(dir, '/'); idx >= 0 {
+			parent, name = dir[:idx], dir[idx+1:]
+		}
+
+		node := tview.NewTreeNode(name + "/").SetSelectable(true).SetExpanded(false)
+		folderNode(parent).AddChild(node)
+		folders[dir] = node
+
+		return node
+	}
+
+	for idx := range snippets {
+		s := &snippets[idx]
+
+		dir := ""
+		if idx := strings.LastIndexByte(s.Path, '/'); idx >= 0 {
+			dir = s.Path[:idx]
+		}
+
+		folderNode(dir).AddChild(tview.NewTreeNode(s.title()).SetSelectable(true).SetReference(s))
+	}
+
+	v.snippetTree.SetCurrentNode(v.snippetRootNode)
+}
+
+func (v *mainView) gotoSnippets() {
+	v.loadSnippets()
+	v.app.SetFocus(v.snippetTree)
+}
+
+// snippetNodeSelected opens a selected snippet in a new query tab, or
+// expands or collapses a selected folder.
+func (v *mainView) snippetNodeSelected(node *tview.TreeNode) {
+	s, ok := node.GetReference().(*snippet)
+	if !ok {
+		node.SetExpanded(!node.IsExpanded())
+
+		return
+	}
+
+	v.newQueryTab(s.Query)
+	v.selectSnippetDB(s)
+	v.app.SetFocus(v.queryInput)
+}
+
+// selectSnippetDB makes the database that a snippet is meant for the current
+// database: the database opened from the snippet's profile, which is opened
+// if necessary, or else a database with the snippet's driver if the
+// current database has a different driver.
+func (v *mainView) selectSnippetDB(s *snippet) {
+	if s.Profile != "" {
+		dbID, ok := v.findOpenDB(func(dbID string) bool { return v.ctrl.getProfileName(dbID) == s.Profile })
+		if !ok {
+			if err := v.ctrl.openProfile(s.Profile); err != nil {
+				v.showError("Opening database %s failed: %v", s.Profile, err)
+
+				return
+			}
+
+			dbID, ok = v.findOpenDB(func(dbID string) bool { return v.ctrl.getProfileName(dbID) == s.Profile })
+		}
+
+		if ok {
+			v.setCurrentDB(dbID)
+		}
+
+		return
+	}
+
+	if s.Driver == "" || (v.currentDB != "" && v.ctrl.getDriver(v.currentDB) == s.Driver) {
+		return
+	}
+
+	if dbID, ok := v.findOpenDB(func(dbID string) bool { return v.ctrl.getDriver(dbID) == s.Driver }); ok {
+		v.setCurrentDB(dbID)
+	}
+}
+
+// findOpenDB returns the first open database for which match returns true.
+func (v *mainView) findOpenDB(match func(dbID string) bool) (string, bool) {
+	for _, node := range v.dbRootNode.GetChildren() {
+		if ref, ok := node.GetReference().(*nodeRef); ok && ref.Type == typeDB && match(ref.DB) {
+			return ref.DB, true
+		}
+	}
+
+	return "", false
+}
+
+// saveSnippet asks for the file name and front-matter of a snippet and
+// saves the query of the current tab as snippet.
+func (v *mainView) saveSnippet() {
+	query := v.queryInput.GetText()
+	if strings.TrimSpace(query) == "" {
+		v.showError("Query is empty")
+
+		return
+	}
+
+	var driver, profile string
+	if v.currentDB != "" {
+		driver, profile = v.ctrl.getDriver(v.currentDB), v.ctrl.getProfileName(v.currentDB)
+	}
+
+	form := tview.NewForm()
+	form.AddInputField("File", "", 60, nil, nil)
+	form.AddInputField("Name", "", 60, nil, nil)
+	form.AddInputField("Description", "", 60, nil, nil)
+	form.AddInputField("Driver", driver, 20, nil, nil)
+	form.AddInputField("Profile", profile, 40, nil, nil)
+
+	text := func(idx int) string {
+		return strings.TrimSpace(form.GetFormItem(idx).(*tview.InputField).GetText())
+	}
+
+	form.AddButton("Save", func() {
+		s := snippet{
+			Path:        text(0),
+			Name:        text(1),
+			Description: text(2),
+			Driver:      text(3),
+			Profile:     text(4),
+			Query:       query,
+		}
+
+		if s.Path == "" {
+			v.showError("No file name for snippet")
+
+			return
+		}
+
+		if _, err := v.ctrl.saveSnippet(s); err != nil {
+			v.showError("Saving snippet failed: %v", err)
+
+			return
+		}
+
+		v.showMainView()
+		v.gotoSnippets()
+	}).AddButton("Cancel", func() {
+		v.showMainView()
+		v.app.SetFocus(v.queryInput)
+	})
+	form.SetCancelFunc(func() {
+		v.showMainView()
+		v.app.SetFocus(v.queryInput)
+	})
+	form.SetBorder(true).SetTitle("Save Query as Snippet (File is relative to snippet directory, e.g. reports/sales.sql)")
+	v.app.SetRoot(form, true)
+}
+
+func (v *mainView) showError(s string, args ...any) {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf(s, args...)).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			v.showMainView()
+		})
+
+	v.app.SetRoot(modal, false)
+}
+
+func (v *mainView) showHelp() {
+	helpScreen := tview.NewTable()
+	helpScreen.SetBorder(true).SetTitle("Help (press ESC to exit)")
+
+	type keyMappingConfig struct {
+		Key         string
+		Operation   string
+		Description string
+	}
+
+	keyMappings := []keyMappingConfig{}
+
+	for keyName, opName := range v.keyMapping {
+		desc := v.operationMapping[opName].Description
+
+		keyMappings = append(keyMappings, keyMappingConfig{Key: keyName, Operation: opName, Description: desc})
+	}
+
+	sort.Slice(keyMappings, func(i, j int) bool {
+		return keyMappings[i].Operation < keyMappings[j].Operation
+	})
+
+	for idx, hdr := range []string{"Key", "Operation", "Description"} {
+		helpScreen.SetCell(0, idx, tview.NewTableCell(hdr).SetAttributes(tcell.AttrBold))
+	}
+
+	for idx, keyMapping := range keyMappings {
+		helpScreen.SetCellSimple(idx+1, 0, tview.Escape(keyMapping.Key)) // e.g. Alt+Rune[n] looks like a style tag.
+		helpScreen.SetCellSimple(idx+1, 1, keyMapping.Operation)
+		helpScreen.SetCellSimple(idx+1, 2, keyMapping.Description)
+	}
+
+	helpScreen.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyESC {
+			v.showMainView()
+
+			return nil
+		}
+
+		return event
+	})
+
+	v.app.SetRoot(helpScreen, true)
+}
+
+const historyLimit = 1000
+
+// showHistory shows the queries that were run on the current database, or
+// on all databases, which can be switched between with a.
+func (v *mainView) showHistory() {
+	dbID := v.currentDB
+
+	entries, err := v.ctrl.getHistory(historyLimit, dbID)
+	if err != nil {
+		v.showError("Loading history failed: %v", err)
+
+		return
+	}
+
+	searchField := tview.NewInputField().SetLabel("Search: ")
+	historyTable := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow)
+
+	setTitle := func() {
+		switch {
+		case v.currentDB == "":
+			layout.SetTitle("History (Enter: load into current tab, n: load into new tab, /: search, ESC: exit)")
+		case dbID == "":
+			layout.SetTitle("History of all databases (Enter: load into current tab, n: load into new tab, a: current database only, /: search, ESC: exit)")
+		default:
+			layout.SetTitle("History of " + tview.Escape(v.ctrl.getDatabaseName(dbID)) + " (Enter: load into current tab, n: load into new tab, a: all databases, /: search, ESC: exit)")
+		}
+	}
+
+	var shown []historyEntry
+
+	filter := func(pattern string) {
+		type match struct {
+			entry historyEntry
+			score int
+		}
+
+		var matches []match
+
+		for _, entry := range entries {
+			if score, ok := fuzzyMatch(pattern, entry.Query); ok {
+				matches = append(matches, match{entry: entry, score: score})
+			}
+		}
+
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].score > matches[j].score
+		})
+
+		shown = shown[:0]
+
+		historyTable.Clear()
+
+		for idx, hdr := range []string{"Time", "Database", "Duration", "Rows", "Status", "Query"} {
+			historyTable.SetCell(0, idx, tview.NewTableCell(hdr).SetAttributes(tcell.AttrBold).SetSelectable(false))
+		}
+
+		for idx, m := range matches {
+			status := "OK"
+			if m.entry.Error != "" {
+				status = "Error: " + m.entry.Error
+			}
+
+			row := idx + 1
+			historyTable.SetCellSimple(row, 0, m.entry.ExecutedAt.Local().Format("2006-01-02 15:04:05"))
+			historyTable.SetCellSimple(row, 1, m.entry.Database)
+			historyTable.SetCellSimple(row, 2, (time.Duration(m.entry.DurationMS) * time.Millisecond).String())
+			historyTable.SetCellSimple(row, 3, fmt.Sprint(m.entry.RowCount))
+			historyTable.SetCell(row, 4, tview.NewTableCell(status).SetMaxWidth(30))
+			historyTable.SetCell(row, 5, tview.NewTableCell(strings.Join(strings.Fields(m.entry.Query), " ")).SetExpansion(1))
+
+			shown = append(shown, m.entry)
+		}
+
+		historyTable.Select(1, 0).ScrollToBeginning()
+	}
+
+	filter("")
+
+	searchField.SetChangedFunc(filter)
+	searchField.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter, tcell.KeyTab, tcell.KeyDown:
+			v.app.SetFocus(historyTable)
+		case tcell.KeyEscape:
+			v.showMainView()
+		}
+	})
+
+	loadEntry := func(newTab bool) {
+		row, _ := historyTable.GetSelection()
+		if row < 1 || row > len(shown) {
+			return
+		}
+
+		query := shown[row-1].Query
+
+		if newTab {
+			v.newQueryTab(query)
+		} else {
+			v.queryInput.SetText(query, true)
+		}
+
+		v.showMainView()
+		v.app.SetFocus(v.queryInput)
+	}
+
+	historyTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEnter:
+			loadEntry(false)
+		case event.Key() == tcell.KeyRune && event.Rune() == 'n':
+			loadEntry(true)
+		case event.Key() == tcell.KeyRune && event.Rune() == 'a' && v.currentDB != "":
+			if dbID == "" {
+				dbID = v.currentDB
+			} else {
+				dbID = ""
+			}
+
+			if entries, err = v.ctrl.getHistory(historyLimit, dbID); err != nil {
+				v.showError("Loading history failed: %v", err)
+
+				return nil
+			}
+
+			filter(searchField.GetText())
+			setTitle()
+		case event.Key() == tcell.KeyRune && event.Rune() == '/', event.Key() == tcell.KeyTab:
+			v.app.SetFocus(searchField)
+		case event.Key() == tcell.KeyESC:
+			v.showMainView()
+		default:
+			return event
+		}
+
+		return nil
+	})
+
+	layout.AddItem(searchField, 1, 0, true).
+		AddItem(historyTable, 0, 1, false)
+	layout.SetBorder(true)
+	setTitle()
+
+	v.app.SetRoot(layout, true)
+}
+
+// explainQuery shows the plan of the selected text or the statement at the
+// cursor as a tree.
+func (v *mainView) explainQuery() {
+	if v.currentDB == "" {
+		v.showError("No database has been selected")
+
+		return
+	}
+
+	query, ok := v.currentStatement()
+	if !ok {
+		return
+	}
+
+	v.runExplain(v.currentDB, query, false)
+}
+
+// runExplain explains query in the background. Like a query, it can be
+// cancelled with cancel-query.
+func (v *mainView) runExplain(dbID, query string, analyze bool) {
+	v.queryMtx.Lock()
+	defer v.queryMtx.Unlock()
+
+	if v.cancelQuery != nil {
+		v.showError("A query is already running")
+
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancelQuery = cancel
+
+	go func() {
+		v.startActivityGauge()
+		defer v.stopActivityGauge()
+
+		plan, err := v.ctrl.explainQuery(ctx, dbID, query, analyze)
+		cancelled := ctx.Err() != nil
+
+		v.queryMtx.Lock()
+		v.cancelQuery = nil
+		v.queryMtx.Unlock()
+		cancel()
+
+		v.app.QueueUpdateDraw(func() {
+			switch {
+			case err != nil && cancelled:
+				v.activityPlaceholder.SetText("Query cancelled")
+			case err != nil:
+				v.showError("Explaining query failed: %v", err)
+			case analyze || !v.ctrl.canAnalyzeQueries(dbID):
+				v.showQueryPlan(plan, nil)
+			default:
+				v.showQueryPlan(plan, func() {
+					v.confirmAnalyze(dbID, query)
+				})
+			}
+		})
+	}()
+}
+
+// confirmAnalyze asks whether query should be run to explain it with its
+// actual times and row counts.
+func (v *mainView) confirmAnalyze(dbID, query string) {
+	modal := tview.NewModal().
+		SetText("EXPLAIN ANALYZE runs the statement. Its changes are rolled back, but side effects like sequence increments remain. Run it?").
+		AddButtons([]string{"Run", "Cancel"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			v.showMainView()
+
+			if buttonLabel == "Run" {
+				v.runExplain(dbID, query, true)
+			}
+		})
+
+	v.app.SetRoot(modal, false)
+}
+
+// showQueryPlan shows plan as a tree. If analyze is set, it is called when
+// the user asks for the plan with actual times and row counts.
+func (v *mainView) showQueryPlan(plan *planNode, analyze func()) {
+	var addNode func(node *planNode) *tview.TreeNode
+
+	addNode = func(node *planNode) *tview.TreeNode {
+		text := tview.Escape(node.Label)
+		if summary := node.summary(); summary != "" {
+			text += "  [yellow]" + tview.Escape(summary)
+		}
+
+		treeNode := tview.NewTreeNode(text).SetSelectable(true)
+
+		for _, detail := range node.Details {
+			treeNode.AddChild(tview.NewTreeNode(tview.Escape(detail)).SetColor(tcell.ColorGray))
+		}
+
+		for _, child := range node.Children {
+			treeNode.AddChild(addNode(child))
+		}
+
+		return treeNode
+	}
+
+	root := addNode(plan)
+
+	planTree := tview.NewTreeView().SetRoot(root).SetCurrentNode(root)
+	planTree.SetBorder(true).SetTitle("Query Plan (Enter: collapse/expand, ESC: exit)")
+	planTree.SetSelectedFunc(func(node *tview.TreeNode) {
+		node.SetExpanded(!node.IsExpanded())
+	})
+
+	if analyze != nil {
+		planTree.SetTitle("Query Plan (Enter: collapse/expand, a: explain with ANALYZE, ESC: exit)")
+		planTree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyRune && event.Rune() == 'a' {
+				analyze()
+
+				return nil
+			}
+
+			return event
+		})
+	}
+	planTree.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			v.showMainView()
+		}
+	})
+
+	v.app.SetRoot(planTree, true)
+}
+
+// showDDL shows the DDL of the table, view or index that is selected in the
+// database tree.
+func (v *mainView) showDDL() {
+	node := v.dbTree.GetCurrentNode()
+	if node == nil {
+		return
+	}
+
+	ref, ok := node.GetReference().(*nodeRef)
+	if !ok || !(ref.Type == typeTable || (ref.Type == typeObject && ref.Kind == objectIndexes)) {
+		v.showError("Select a table, view or index in the database tree")
+
+		return
+	}
+
+	name := ref.Table
+	if ref.Type == typeObject {
+		name = ref.Name
+	}
+
+	go func() {
+		v.startActivityGauge()
+		defer v.stopActivityGauge()
+
+		ddl, err := v.ctrl.getDDL(ref.DB, ref.Schema, name, ref.Kind)
+
+		v.app.QueueUpdateDraw(func() {
+			if err != nil {
+				v.showError("Getting DDL of %s failed: %v", name, err)
+
+				return
+			}
+
+			v.showDDLViewer(ref.DB, name, ddl)
+		})
+	}()
+}
+
+// showDDLViewer shows ddl in a read-only editor, in which text can be
+// selected and copied, and from which the DDL can be opened in a new query
+// tab.
+func (v *mainView) showDDLViewer(dbID, name, ddl string) {
+	viewer := newQueryEditor()
+	viewer.setColors(v.queryInput.colors)
+
+	viewer.setDialect(driverDialect(v.ctrl.getDriver(dbID)))
+	viewer.SetText(ddl, false)
+	viewer.SetClipboard(v.copyToClipboard, v.pasteFromClipboard)
+	viewer.SetBorder(true).SetTitle("DDL of " + name + " (Ctrl-L: select all, Ctrl-Q: copy, Ctrl-T: open in new query tab, ESC: exit)")
+
+	viewer.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			v.showMainView()
+		case tcell.KeyCtrlT:
+			v.showMainView()
+			v.newQueryTab(ddl)
+			v.app.SetFocus(v.queryInput)
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyLeft, tcell.KeyRight, tcell.KeyHome, tcell.KeyEnd,
+			tcell.KeyPgUp, tcell.KeyPgDn, tcell.KeyCtrlL, tcell.KeyCtrlQ:
+			// keys that move the cursor or select and copy text.
+			return event
+		}
+
+		return nil
+	})
+
+	v.app.SetRoot(viewer, true)
+}
+
+// selectedTable returns the table or view that is selected in the database
+// tree, showing an error if no table is selected.
+func (v *mainView) selectedTable() (*nodeRef, bool) {
+	if node := v.dbTree.GetCurrentNode(); node != nil {
+		if ref, ok := node.GetReference().(*nodeRef); ok && ref.Type == typeTable {
+			return ref, true
+		}
+	}
+
+	v.showError("Select a table or view in the database tree")
+
+	return nil, false
+}
+
+// previewTable makes the database of the selected table the current
+// database and shows the first page of rows of the table.
+func (v *mainView) previewTable() {
+	ref, ok := v.selectedTable()
+	if !ok {
+		return
+	}
+
+	v.setCurrentDB(ref.DB)
+	v.runQuery(previewQuery(v.ctrl.getDriver(ref.DB), ref.Schema, ref.Table, v.pageSize))
+}
+
+// generateTemplate asks which statement to generate for the selected table
+// and opens it in a new query tab.
+func (v *mainView) generateTemplate() {
+	ref, ok := v.selectedTable()
+	if !ok {
+		return
+	}
+
+	modal := tview.NewModal().
+		SetText("Generate statement for " + ref.Table).
+		AddButtons(append(queryTemplateNames, "Cancel")).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			v.showMainView()
+
+			if buttonIndex < 0 || buttonIndex >= len(queryTemplateNames) {
+				return
+			}
+
+			go func() {
+				v.startActivityGauge()
+				defer v.stopActivityGauge()
+
+				columns, err := v.tableColumns(ref)
+
+				v.app.QueueUpdateDraw(func() {
+					if err != nil {
+						v.showError("Listing columns for %s failed: %v", ref.Table, err)
+
+						return
+					}
+
+					v.newQueryTab(generateTemplate(queryTemplate(buttonIndex), v.ctrl.getDriver(ref.DB), ref.Schema, ref.Table, columns))
+					v.app.SetFocus(v.queryInput)
+				})
+			}()
+		})
+
+	v.app.SetRoot(modal, false)
+}
+
+// copyToClipboard keeps text for pasting it in the query input, and copies
+// it to the system clipboard.
+func (v *mainView) copyToClipboard(text string) {
+	v.clipboard = text
+
+	if err := copyToSystemClipboard(v.clipboardCfg, text); err != nil {
+		v.showError("Copying to clipboard failed: %v", err)
+	}
+}
+
+func (v *mainView) pasteFromClipboard() string {
+	return v.clipboard
+}
+
+const maxCompletionHeight = 10
+
+// showCompletions shows a popup below the cursor of the query input that
+// lists the completions for the word at the cursor. If there is only a
+// single completion, it is inserted right away.
+func (v *mainView) showCompletions() {
+	if v.app.GetFocus() != v.queryInput {
+		return
+	}
+
+	query := v.queryInput.GetText()
+	_, pos, _ := v.queryInput.GetSelection()
+	dbID := v.currentDB
+
+	go func() {
+		start, completions, err := v.ctrl.getCompletions(dbID, query, pos)
+
+		v.app.QueueUpdateDraw(func() {
+			if err != nil {
+				v.showError("Loading completions failed: %v", err)
+
+				return
+			}
+
+			v.completionPopup(start, pos, query[start:pos], completions)
+		})
+	}()
+}
+
+func (v *mainView) completionPopup(start, end int, prefix string, completions []completion) {
+	switch len(completions) {
+	case 0:
+		return
+	case 1:
+		v.queryInput.Replace(start, end, completions[0].Text)
+
+		return
+	}
+
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true)
+
+	closePopup := func() {
+		v.app.SetRoot(v.layout, true)
+		v.app.SetFocus(v.queryInput)
+	}
+
+	shown := completions
+
+	fill := func() {
+		list.Clear()
+
+		for _, c := range shown {
+			color := "white"
+
+			switch c.Kind {
+			case completionColumn:
+				color = "yellow"
+			case completionTable:
+				color = "green"
+			}
+
+			list.AddItem(fmt.Sprintf("[%s]%s [gray]%s", color, tview.Escape(c.Text), tview.Escape(c.Detail)), "", 0, nil)
+		}
+	}
+
+	fill()
+
+	list.SetSelectedFunc(func(idx int, _, _ string, _ rune) {
+		v.queryInput.Replace(start, end, shown[idx].Text)
+		closePopup()
+	})
+	list.SetDoneFunc(closePopup)
+
+	// typing continues the word in the query input and narrows down the
+	// completions.
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyRune && event.Rune() < utf8.RuneSelf && isWordChar(byte(event.Rune())):
+			v.queryInput.Replace(end, end, string(event.Rune()))
+			end++
+			prefix += string(event.Rune())
+		case (event.Key() == tcell.KeyBackspace || event.Key() == tcell.KeyBackspace2) && end > start:
+			v.queryInput.Replace(end-1, end, "")
+			end--
+			prefix = prefix[:len(prefix)-1]
+		default:
+			return event
+		}
+
+		shown = filterCompletions(completions, prefix)
+		if len(shown) == 0 {
+			closePopup()
+
+			return nil
+		}
+
+		fill()
+
+		x, y, width, height := list.GetRect()
+		if len(shown)+2 < height {
+			list.SetRect(x, y, width, len(shown)+2)
+		}
+
+		return nil
+	})
+
+	width := 0
+	for _, c := range completions {
+		if w := tview.TaggedStringWidth(c.Text + " " + c.Detail); w > width {
+			width = w
+		}
+	}
+
+	width += 2
+	height := len(completions) + 2
+
+	if height > maxCompletionHeight+2 {
+		height = maxCompletionHeight + 2
+	}
+
+	x, y, _, _ := v.queryInput.GetInnerRect()
+	_, _, row, col := v.queryInput.GetCursor()
+	offsetRow, offsetCol := v.queryInput.GetOffset()
+	_, _, screenWidth, screenHeight := v.layout.GetRect()
+
+	x += col - offsetCol
+	y += row - offsetRow + 1
+
+	if x+width > screenWidth {
+		x = screenWidth - width
+	}
+
+	if y+height > screenHeight {
+		y -= height + 1
+	}
+
+	list.SetRect(x, y, width, height)
+
+	pages := tview.NewPages().
+		AddPage("main", v.layout, true, true).
+		AddPage("completions", list, false, true)
+
+	v.app.SetRoot(pages, true)
+	v.app.SetFocus(list)
+}
+
+func (v *mainView) refreshSchema() {
+	if v.currentDB == "" {
+		v.showError("No database selected")
+
+		return
+	}
+
+	v.ctrl.refreshSchema(v.currentDB)
+}
+
+func (v *mainView) run() error {
+	v.loadSnippets()
+
 	if err := v.app.Run(); err != nil {
 		return fmt.Errorf("running application failed: %w", err)
 	}